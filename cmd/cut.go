@@ -0,0 +1,30 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+
+	"lem-in/utils"
+)
+
+func printCut(g *utils.Graph, asJSON bool) {
+	report := utils.Bottlenecks(g)
+	if asJSON {
+		if report == nil {
+			report = []utils.Bottleneck{}
+		}
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		enc.Encode(report)
+		return
+	}
+	if len(report) == 0 {
+		fmt.Println("no bottleneck rooms between start and end")
+		return
+	}
+	fmt.Printf("%-16s %8s %14s %8s\n", "room", "turns", "bypass turns", "saved")
+	for _, b := range report {
+		fmt.Printf("%-16s %8d %14d %8d\n", b.Room, b.Turns, b.BypassTurns, b.Turns-b.BypassTurns)
+	}
+}
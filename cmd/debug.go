@@ -0,0 +1,279 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"os/exec"
+	"slices"
+	"strconv"
+	"strings"
+
+	"lem-in/utils"
+)
+
+const (
+	mapWidth  = 60
+	mapHeight = 15
+	// checkpointEvery is how many turns apart the debugger keeps a copy
+	// of the simulation to step back from.
+	checkpointEvery = 256
+)
+
+// debugger steps through a simulation. Only the moves of each turn and a
+// copy of the simulation every checkpointEvery turns are kept, so going
+// back replays at most that many turns from the nearest checkpoint.
+type debugger struct {
+	g           *utils.Graph
+	paths       [][]*utils.Room
+	sim         *utils.Simulation // state after turn
+	checkpoints []*utils.Simulation
+	moves       [][]utils.Move // moves of each turn, none for turn 0
+	turn        int
+	ant         int // highlighted ant, 0 for none
+	prompt      string
+	input       string
+}
+
+// runDebug shows the schedule of g in the terminal until the user quits.
+func runDebug(g *utils.Graph) {
+	paths := utils.FindPaths(g)
+	if len(paths) == 0 {
+		failNoPath()
+	}
+	d := newDebugger(g, paths)
+	restore, err := rawMode()
+	if err != nil {
+		fmt.Println("ERROR: cannot switch the terminal to raw mode")
+		fmt.Println("Reason: " + err.Error())
+		os.Exit(1)
+	}
+	defer restore()
+
+	in := bufio.NewReader(os.Stdin)
+	for {
+		fmt.Print("\x1b[H\x1b[2J" + strings.Join(d.render(), "\r\n") + "\r\n")
+		b, err := in.ReadByte()
+		if err != nil || !d.key(b, in) {
+			return
+		}
+	}
+}
+
+// newDebugger runs the simulation once to record its moves and
+// checkpoints, and starts at turn 0.
+func newDebugger(g *utils.Graph, paths [][]*utils.Room) *debugger {
+	d := &debugger{g: g, paths: paths, moves: [][]utils.Move{nil}}
+	sim := utils.NewSimulation(g, paths, utils.ObjectiveNone)
+	for turn := 0; ; turn++ {
+		if turn%checkpointEvery == 0 {
+			d.checkpoints = append(d.checkpoints, sim.Clone())
+		}
+		if sim.Done() {
+			break
+		}
+		d.moves = append(d.moves, sim.Step())
+	}
+	d.sim = d.checkpoints[0].Clone()
+	return d
+}
+
+// turns returns the number of turns the simulation lasts.
+func (d *debugger) turns() int {
+	return len(d.moves) - 1
+}
+
+// seek brings the simulation to turn, stepping forward from the current
+// turn or from the last checkpoint before it.
+func (d *debugger) seek(turn int) {
+	turn = clamp(turn, 0, d.turns())
+	if turn < d.turn || turn-d.turn >= checkpointEvery {
+		d.sim = d.checkpoints[turn/checkpointEvery].Clone()
+		d.turn = turn / checkpointEvery * checkpointEvery
+	}
+	for ; d.turn < turn; d.turn++ {
+		d.sim.Step()
+	}
+}
+
+// rawMode puts the terminal in raw mode with stty and returns a function
+// restoring the previous settings.
+func rawMode() (func(), error) {
+	get := exec.Command("stty", "-g")
+	get.Stdin = os.Stdin
+	saved, err := get.Output()
+	if err != nil {
+		return nil, err
+	}
+	set := exec.Command("stty", "raw", "-echo")
+	set.Stdin = os.Stdin
+	if err := set.Run(); err != nil {
+		return nil, err
+	}
+	return func() {
+		reset := exec.Command("stty", strings.TrimSpace(string(saved)))
+		reset.Stdin = os.Stdin
+		reset.Run()
+		fmt.Print("\x1b[H\x1b[2J")
+	}, nil
+}
+
+// key handles one key press and reports whether to keep running.
+func (d *debugger) key(b byte, in *bufio.Reader) bool {
+	if d.prompt != "" {
+		switch {
+		case b >= '0' && b <= '9':
+			d.input += string(b)
+		case b == 127 || b == 8:
+			if d.input != "" {
+				d.input = d.input[:len(d.input)-1]
+			}
+		case b == '\r' || b == '\n':
+			n, err := strconv.Atoi(d.input)
+			if err == nil && d.prompt == "turn" {
+				d.seek(n)
+			} else if err == nil && d.prompt == "ant" {
+				d.ant = clamp(n, 0, d.g.Ants)
+			}
+			d.prompt, d.input = "", ""
+		case b == 27:
+			d.prompt, d.input = "", ""
+		}
+		return true
+	}
+	switch b {
+	case 'q', 3:
+		return false
+	case 'n', ' ', 'l':
+		d.seek(d.turn + 1)
+	case 'p', 'h':
+		d.seek(d.turn - 1)
+	case 'g':
+		d.prompt = "turn"
+	case 'a':
+		d.prompt = "ant"
+	case 27:
+		// Arrow keys arrive as ESC [ C (right) and ESC [ D (left).
+		if next, _ := in.ReadByte(); next == '[' {
+			switch arrow, _ := in.ReadByte(); arrow {
+			case 'C':
+				d.seek(d.turn + 1)
+			case 'D':
+				d.seek(d.turn - 1)
+			}
+		}
+	}
+	return true
+}
+
+func (d *debugger) render() []string {
+	s := d.sim.Snapshot()
+	lines := []string{
+		fmt.Sprintf("turn %d/%d   ants %d   finished %d", s.Turn, d.turns(), d.g.Ants, len(s.Finished)),
+		"",
+	}
+	lines = append(lines, d.drawMap(s)...)
+	lines = append(lines, "")
+	for i, p := range d.paths {
+		cells := make([]string, len(p))
+		for j, r := range p {
+			cells[j] = r.Name
+			if ants := s.Rooms[r]; len(ants) > 0 {
+				names := make([]string, len(ants))
+				for k, ant := range ants {
+					names[k] = d.label("L"+strconv.Itoa(ant), ant)
+				}
+				cells[j] = r.Name + "(" + strings.Join(names, " ") + ")"
+			}
+		}
+		lines = append(lines, fmt.Sprintf("path %d: %s", i+1, strings.Join(cells, " - ")))
+		waiting := make([]string, len(s.Waiting[i]))
+		for j, ant := range s.Waiting[i] {
+			waiting[j] = d.label("L"+strconv.Itoa(ant), ant)
+		}
+		lines = append(lines, "  waiting: "+strings.Join(waiting, " "))
+	}
+	moves := make([]string, len(s.Moves))
+	for i, m := range s.Moves {
+		moves[i] = d.label(fmt.Sprintf("L%d-%s", m.Ant, m.Room.Name), m.Ant)
+	}
+	lines = append(lines, "", "moves: "+strings.Join(moves, " "))
+	if d.ant > 0 {
+		lines = append(lines, fmt.Sprintf("route of L%d: %s", d.ant, d.route(d.ant)))
+	}
+	lines = append(lines, "", "[n/right] next  [p/left] back  [g] go to turn  [a] highlight ant  [q] quit")
+	if d.prompt != "" {
+		lines = append(lines, d.prompt+": "+d.input)
+	}
+	return lines
+}
+
+// label shows text in reverse video when it concerns the highlighted ant.
+func (d *debugger) label(text string, ant int) string {
+	if ant == d.ant {
+		return "\x1b[7m" + text + "\x1b[0m"
+	}
+	return text
+}
+
+// route lists the rooms ant has entered so far and the turn of each.
+func (d *debugger) route(ant int) string {
+	parts := []string{d.g.Start.Name + "@0"}
+	for turn, moves := range d.moves[1 : d.turn+1] {
+		for _, m := range moves {
+			if m.Ant == ant {
+				parts = append(parts, fmt.Sprintf("%s@%d", m.Room.Name, turn+1))
+			}
+		}
+	}
+	return strings.Join(parts, " ")
+}
+
+// drawMap places every room at its scaled coordinates: S and E mark start
+// and end, o an empty room, # an occupied one and @ the highlighted ant.
+func (d *debugger) drawMap(s utils.Snapshot) []string {
+	minX, minY, maxX, maxY := d.g.Start.X, d.g.Start.Y, d.g.Start.X, d.g.Start.Y
+	for _, r := range d.g.Rooms {
+		minX, maxX = min(minX, r.X), max(maxX, r.X)
+		minY, maxY = min(minY, r.Y), max(maxY, r.Y)
+	}
+	grid := make([][]byte, mapHeight)
+	for i := range grid {
+		grid[i] = []byte(strings.Repeat(" ", mapWidth))
+	}
+	for _, r := range d.g.Rooms {
+		col := scale(r.X, minX, maxX, mapWidth)
+		row := scale(r.Y, minY, maxY, mapHeight)
+		c := byte('o')
+		switch ants := s.Rooms[r]; {
+		case r == d.g.Start:
+			c = 'S'
+		case r == d.g.End:
+			c = 'E'
+		case slices.Contains(ants, d.ant):
+			c = '@'
+		case len(ants) > 0:
+			c = '#'
+		}
+		grid[row][col] = c
+	}
+	lines := make([]string, len(grid))
+	for i, row := range grid {
+		lines[i] = "  " + string(row)
+	}
+	return lines
+}
+
+// scale maps v from [lo, hi] onto [0, size). It works in floating point
+// because the coordinate span times size can overflow an int.
+func scale(v, lo, hi, size int) int {
+	if hi == lo {
+		return 0
+	}
+	f := (float64(v) - float64(lo)) / (float64(hi) - float64(lo))
+	return clamp(int(f*float64(size-1)), 0, size-1)
+}
+
+func clamp(v, lo, hi int) int {
+	return max(lo, min(v, hi))
+}
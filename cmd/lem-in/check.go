@@ -0,0 +1,50 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"slices"
+
+	"lem-in/internal/utils"
+)
+
+// runCheck reads a solution for g on stdin, one line per turn, and reports
+// whether it is valid. The solution may start with a copy of the colony,
+// as printed by lem-in itself, which is skipped up to the first empty
+// line.
+func runCheck(g *utils.Graph, lines []string) {
+	var moves []string
+	scanner := bufio.NewScanner(os.Stdin)
+	// A turn moves up to one ant per tunnel lane, and wide tunnels make
+	// for long lines.
+	scanner.Buffer(nil, 1<<30)
+	for scanner.Scan() {
+		moves = append(moves, scanner.Text())
+	}
+	if err := scanner.Err(); err != nil {
+		fmt.Println("ERROR: " + err.Error())
+		os.Exit(1)
+	}
+	if len(moves) > 0 && len(lines) > 0 && moves[0] == lines[0] {
+		for i, l := range moves {
+			if l == "" {
+				moves = moves[i+1:]
+				break
+			}
+		}
+	}
+	if err := utils.CheckMoves(g, moves); err != nil {
+		if e, ok := err.(utils.LemError); ok {
+			fmt.Println(e.Msg)
+			fmt.Println("Reason: " + e.Reason)
+		} else {
+			fmt.Println(err.Error())
+		}
+		if !slices.Contains(moves, "") {
+			fmt.Fprintln(os.Stderr, "note: the solution has no empty turns; print it with --idle-turns if some turns see no ant arrive")
+		}
+		os.Exit(1)
+	}
+	fmt.Printf("OK: %d ants in %d turns\n", g.Ants, len(moves))
+}
@@ -0,0 +1,83 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"lem-in/internal/utils"
+)
+
+// parseMap parses the colony in data.
+func parseMap(t *testing.T, data string) *utils.Graph {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "in.txt")
+	if err := os.WriteFile(path, []byte(data), 0644); err != nil {
+		t.Fatalf("write temp file: %v", err)
+	}
+	g, _, err := utils.ParseInputWith(path, utils.ParseOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return g
+}
+
+func TestDebugSeek(t *testing.T) {
+	// One path of three rooms: 700 ants take 703 turns, about three
+	// checkpoints' worth.
+	g := parseMap(t, "700\n##start\ns 0 0\na 1 0\nb 2 0\nc 3 0\n##end\ne 4 0\ns-a\na-b\nb-c\nc-e\n")
+	d := newDebugger(g, utils.FindPaths(g))
+	if d.turns() != 703 {
+		t.Fatalf("turns = %d, want 703", d.turns())
+	}
+	if len(d.checkpoints) != 3 {
+		t.Errorf("checkpoints = %d, want 3", len(d.checkpoints))
+	}
+
+	// Every turn as reached by stepping from the start.
+	want := []utils.Snapshot{}
+	sim := utils.NewSimulation(g, d.paths, utils.ObjectiveNone)
+	for {
+		want = append(want, sim.Snapshot())
+		if sim.Done() {
+			break
+		}
+		sim.Step()
+	}
+
+	for _, turn := range []int{1, 255, 256, 257, 600, 3, 512, 511, 703, 0, 300} {
+		d.seek(turn)
+		if d.turn != turn {
+			t.Errorf("seek(%d) left the debugger at turn %d", turn, d.turn)
+		}
+		if got := d.sim.Snapshot(); !reflect.DeepEqual(got, want[turn]) {
+			t.Errorf("seek(%d) differs from stepping from the start", turn)
+		}
+	}
+	d.seek(-5)
+	if d.turn != 0 {
+		t.Errorf("seek(-5) gave turn %d, want 0", d.turn)
+	}
+	d.seek(10000)
+	if d.turn != 703 || !reflect.DeepEqual(d.sim.Snapshot(), want[703]) {
+		t.Errorf("seek(10000) gave turn %d, want 703", d.turn)
+	}
+}
+
+func TestDebugMapHugeCoordinates(t *testing.T) {
+	g := parseMap(t, "1\n##start\ns -9223372036854775807 -9223372036854775807\n"+
+		"a 0 0\n##end\ne 9223372036854775807 9223372036854775807\ns-a\na-e\n")
+	d := newDebugger(g, utils.FindPaths(g))
+	lines := d.drawMap(d.sim.Snapshot())
+	if len(lines) != mapHeight {
+		t.Fatalf("map has %d lines, want %d", len(lines), mapHeight)
+	}
+	if !strings.Contains(lines[0], "S") || !strings.Contains(lines[mapHeight-1], "E") {
+		t.Errorf("start and end not in the corners:\n%s", strings.Join(lines, "\n"))
+	}
+	if !strings.Contains(lines[mapHeight/2], "o") {
+		t.Errorf("room a not in the middle row:\n%s", strings.Join(lines, "\n"))
+	}
+}
@@ -1,19 +1,193 @@
 package main
 
 import (
-
+	"flag"
 	"fmt"
 	"os"
+	"strings"
 
 	"lem-in/internal/utils"
 )
 
+const usage = `Usage: lem-in [--objective=none|moves|arrival|paths] [--summary[=json]] [--itinerary] [--idle-turns] [--multi] [--colonies] <file>
+       lem-in --max-ants-for-turns=N <file>
+       lem-in stats <file>
+       lem-in cut [--json] <file>
+       lem-in suggest [--add-links=k] [--max-distance=d] <file>
+       lem-in sensitivity <file>
+       lem-in sweep [--ants=from..to] <file>
+       lem-in debug <file>
+       lem-in check [--multi] [--colonies] <file> < solution
+       lem-in scenario --events=FILE [--objective=...] [--idle-turns] <file>
+Every command also takes --cost=links|euclid.`
+
 func main() {
-	if len(os.Args) != 2 {
-		fmt.Println("Usage: lem-in <file>")
+	if len(os.Args) > 1 {
+		switch os.Args[1] {
+		case "stats":
+			fs, opts := newFlagSet("stats")
+			graph, _ := loadWith(fileArg(fs, os.Args[2:]), *opts)
+			printStats(graph)
+			return
+		case "cut":
+			fs, opts := newFlagSet("cut")
+			asJSON := fs.Bool("json", false, "print the report as JSON")
+			graph, _ := loadWith(fileArg(fs, os.Args[2:]), *opts)
+			printCut(graph, *asJSON)
+			return
+		case "suggest":
+			fs, opts := newFlagSet("suggest")
+			k := fs.Int("add-links", 1, "number of tunnels to suggest")
+			maxDist := fs.Float64("max-distance", 0, "only join rooms at most this far apart (0 for no limit)")
+			graph, _ := loadWith(fileArg(fs, os.Args[2:]), *opts)
+			printSuggestions(graph, *k, *maxDist)
+			return
+		case "sensitivity":
+			fs, opts := newFlagSet("sensitivity")
+			graph, _ := loadWith(fileArg(fs, os.Args[2:]), *opts)
+			printSensitivity(graph)
+			return
+		case "sweep":
+			fs, opts := newFlagSet("sweep")
+			ants := fs.String("ants", "", "range of ant counts, e.g. 1..100000 (default 1..ants in file)")
+			graph, _ := loadWith(fileArg(fs, os.Args[2:]), *opts)
+			printSweep(graph, *ants)
+			return
+		case "debug":
+			fs, opts := newFlagSet("debug")
+			graph, _ := loadWith(fileArg(fs, os.Args[2:]), *opts)
+			runDebug(graph)
+			return
+		case "check":
+			fs, opts := newFlagSet("check")
+			fs.BoolVar(&opts.MultiTerminal, "multi", false, "allow several ##start and ##end rooms")
+			fs.BoolVar(&opts.Colonies, "colonies", false, "allow several colonies, \"##start NAME N\" and \"##end NAME\"")
+			graph, lines := loadWith(fileArg(fs, os.Args[2:]), *opts)
+			runCheck(graph, lines)
+			return
+		case "scenario":
+			fs, opts := newFlagSet("scenario")
+			events := fs.String("events", "", "scenario `file` listing events such as \"5 collapse a-b\" or \"8 open c-d\"")
+			objName := fs.String("objective", "none", "secondary objective among the fastest solutions: none, moves, arrival or paths")
+			idle := fs.Bool("idle-turns", false, "print the turns in which no ant arrives in a room as empty lines")
+			graph, lines := loadWith(fileArg(fs, os.Args[2:]), *opts)
+			obj, err := utils.ParseObjective(*objName)
+			if err != nil || *events == "" {
+				if err != nil {
+					fmt.Fprintln(os.Stderr, err)
+				}
+				fs.Usage()
+				os.Exit(1)
+			}
+			runScenario(graph, lines, *events, obj, *idle)
+			return
+		}
+	}
+	fs, opts := newFlagSet("lem-in")
+	budget := fs.Int("max-ants-for-turns", 0, "report how many ants can arrive within N turns instead of solving")
+	objName := fs.String("objective", "", "secondary objective among the fastest solutions: none, moves, arrival or paths")
+	itinerary := fs.Bool("itinerary", false, "print each ant's rooms and arrival turns instead of the moves per turn")
+	idle := fs.Bool("idle-turns", false, "print the turns in which no ant arrives in a room as empty lines")
+	var summary summaryFlag
+	fs.Var(&summary, "summary", "print a solution summary on stderr, as text or `json`")
+	fs.BoolVar(&opts.MultiTerminal, "multi", false, "allow several ##start and ##end rooms, \"##start N\" holding N ants")
+	fs.BoolVar(&opts.Colonies, "colonies", false, "allow several colonies, \"##start NAME N\" and \"##end NAME\"")
+	graph, lines := loadWith(fileArg(fs, os.Args[1:]), *opts)
+	if *budget > 0 {
+		printMaxAnts(graph, *budget)
+		return
+	}
+	obj := utils.ObjectiveNone
+	if *objName != "" {
+		var err error
+		if obj, err = utils.ParseObjective(*objName); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			fs.Usage()
+			os.Exit(1)
+		}
+	}
+	paths := utils.FindPathsWith(graph, obj)
+	if len(paths) == 0 {
+		failNoPath()
+	}
+	for _, l := range lines {
+		fmt.Println(l)
+	}
+	fmt.Println()
+	if *itinerary {
+		for i, visits := range utils.Itineraries(graph, paths, obj) {
+			fmt.Println(formatItinerary(graph.AntLabel(i+1), visits))
+		}
+	} else {
+		printMoves(utils.SimulateWith(graph, paths, obj), *idle)
+	}
+	if summary == "" && *objName != "" {
+		summary = "text"
+	}
+	if summary != "" {
+		printSummary(utils.Summarize(graph, paths, obj), summary == "json")
+	}
+	printLateAnts(utils.LateAnts(graph, paths, obj))
+}
+
+// newFlagSet returns the flags of a command, with --cost already defined
+// and recorded in the returned parse options.
+func newFlagSet(name string) (*flag.FlagSet, *utils.ParseOptions) {
+	fs := flag.NewFlagSet(name, flag.ExitOnError)
+	fs.Usage = func() {
+		fmt.Fprintln(os.Stderr, usage)
+		fs.PrintDefaults()
+	}
+	opts := &utils.ParseOptions{}
+	fs.Var((*costFlag)(&opts.Euclid), "cost", "tunnel crossing `time`: links (##length, 1 by default) or euclid (distance between the rooms, rounded up)")
+	return fs, opts
+}
+
+// costFlag is --cost: "links" or "euclid", which sets it.
+type costFlag bool
+
+func (f *costFlag) String() string {
+	if *f {
+		return "euclid"
+	}
+	return "links"
+}
+
+func (f *costFlag) Set(v string) error {
+	switch v {
+	case "links":
+		*f = false
+	case "euclid":
+		*f = true
+	default:
+		return fmt.Errorf("want links or euclid")
+	}
+	return nil
+}
+
+// fileArg parses args, which may mix flags and the colony file name, and
+// returns the file name.
+func fileArg(fs *flag.FlagSet, args []string) string {
+	var files []string
+	for {
+		fs.Parse(args)
+		if fs.NArg() == 0 {
+			break
+		}
+		files = append(files, fs.Arg(0))
+		args = fs.Args()[1:]
+	}
+	if len(files) != 1 {
+		fs.Usage()
 		os.Exit(1)
 	}
-	graph, lines, err := utils.ParseInput(os.Args[1])
+	return files[0]
+}
+
+// loadWith parses the colony file with the extensions enabled in opts and
+// exits with the parser's message on error.
+func loadWith(path string, opts utils.ParseOptions) (*utils.Graph, []string) {
+	graph, lines, err := utils.ParseInputWith(path, opts)
 	if err != nil {
 		if e, ok := err.(utils.LemError); ok {
 			fmt.Println(e.Msg)
@@ -23,18 +197,51 @@ func main() {
 		}
 		os.Exit(1)
 	}
-	paths := utils.FindPaths(graph)
-	if len(paths) == 0 {
-		fmt.Println("ERROR: invalid data format")
-		fmt.Println("Reason: no path from start to end")
-		os.Exit(1)
+	return graph, lines
+}
+
+// formatPath lists the rooms of a path separated by spaces.
+func formatPath(p []*utils.Room) string {
+	names := make([]string, len(p))
+	for i, r := range p {
+		names[i] = r.Name
 	}
-	for _, l := range lines {
-		fmt.Println(l)
+	return strings.Join(names, " ")
+}
+
+// formatItinerary renders an ant's visits as "L7: start@0 h@3 end@4".
+func formatItinerary(ant string, visits []utils.Visit) string {
+	parts := make([]string, len(visits))
+	for i, v := range visits {
+		parts[i] = fmt.Sprintf("%s@%d", v.Room.Name, v.Turn)
 	}
-	fmt.Println()
-	for _, m := range utils.SimulateMulti(graph, paths) {
+	return fmt.Sprintf("L%s: %s", ant, strings.Join(parts, " "))
+}
+
+// printMoves prints moves one turn per line, leaving out the turns in
+// which no ant arrives in a room unless idle is set.
+func printMoves(moves []string, idle bool) {
+	if !idle {
+		moves = utils.ArrivalTurns(moves)
+	}
+	for _, m := range moves {
 		fmt.Println(m)
 	}
+}
+
+// fail prints err the way the errors of the input are printed and exits.
+func fail(err error) {
+	if e, ok := err.(utils.LemError); ok {
+		fmt.Println(e.Msg)
+		fmt.Println("Reason: " + e.Reason)
+	} else {
+		fmt.Println(err.Error())
+	}
+	os.Exit(1)
+}
 
+func failNoPath() {
+	fmt.Println("ERROR: invalid data format")
+	fmt.Println("Reason: no path from start to end")
+	os.Exit(1)
 }
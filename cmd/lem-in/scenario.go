@@ -0,0 +1,35 @@
+package main
+
+import (
+	"fmt"
+	"os"
+
+	"lem-in/internal/utils"
+)
+
+// runScenario solves g, plays the events of the scenario file as the
+// turns go by and prints the moves, the idle turns too if asked, then
+// reports on stderr how many turns the events cost.
+func runScenario(g *utils.Graph, lines []string, eventsPath string, obj utils.Objective, idle bool) {
+	events, err := utils.ParseScenario(eventsPath)
+	if err == nil {
+		var res utils.ScenarioResult
+		if res, err = utils.RunScenario(g, events, obj); err == nil {
+			for _, l := range lines {
+				fmt.Println(l)
+			}
+			fmt.Println()
+			printMoves(res.Moves, idle)
+			fmt.Fprintf(os.Stderr, "disruption: %+d turns (%d instead of %d), ants rerouted on their way: %d\n",
+				res.ExtraTurns(), res.Turns, res.BaselineTurns, res.Rerouted)
+			return
+		}
+	}
+	if e, ok := err.(utils.LemError); ok {
+		fmt.Println(e.Msg)
+		fmt.Println("Reason: " + e.Reason)
+	} else {
+		fmt.Println(err.Error())
+	}
+	os.Exit(1)
+}
@@ -0,0 +1,38 @@
+package main
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"lem-in/internal/utils"
+)
+
+func printStats(g *utils.Graph) {
+	s := utils.ComputeStats(g)
+	degrees := make([]int, 0, len(s.Degrees))
+	for d := range s.Degrees {
+		degrees = append(degrees, d)
+	}
+	sort.Ints(degrees)
+	dist := make([]string, len(degrees))
+	for i, d := range degrees {
+		dist[i] = fmt.Sprintf("%d:%d", d, s.Degrees[d])
+	}
+	dead := "none"
+	if len(s.DeadRooms) > 0 {
+		dead = strings.Join(s.DeadRooms, " ")
+	}
+	shortest := "none"
+	if s.Distance >= 0 {
+		shortest = fmt.Sprint(s.Distance)
+	}
+	fmt.Println("rooms:", s.Rooms)
+	fmt.Println("links:", s.Links)
+	fmt.Println("degrees:", strings.Join(dist, " "))
+	fmt.Println("components:", s.Components)
+	fmt.Println("shortest path:", shortest)
+	fmt.Println("disjoint paths:", s.DisjointPaths)
+	fmt.Println("diameter:", s.Diameter)
+	fmt.Println("dead rooms:", dead)
+}
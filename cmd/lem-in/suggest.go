@@ -0,0 +1,24 @@
+package main
+
+import (
+	"fmt"
+
+	"lem-in/internal/utils"
+)
+
+func printSuggestions(g *utils.Graph, k int, maxDist float64) {
+	turns := utils.PathTurns(g.Ants, utils.FindPaths(g))
+	if turns == 0 {
+		fmt.Println("turns now: no path")
+	} else {
+		fmt.Println("turns now:", turns)
+	}
+	suggestions := utils.SuggestLinks(g, k, maxDist)
+	if len(suggestions) == 0 {
+		fmt.Println("no new tunnel reduces the turn count")
+		return
+	}
+	for _, s := range suggestions {
+		fmt.Printf("add %s-%s: %d turns\n", s.From, s.To, s.Turns)
+	}
+}
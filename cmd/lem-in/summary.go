@@ -0,0 +1,75 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+
+	"lem-in/internal/utils"
+)
+
+// summaryFlag is "", "text" or "json"; a bare --summary means text.
+type summaryFlag string
+
+func (f *summaryFlag) String() string { return string(*f) }
+
+func (f *summaryFlag) IsBoolFlag() bool { return true }
+
+func (f *summaryFlag) Set(v string) error {
+	switch v {
+	case "true", "text":
+		*f = "text"
+	case "false":
+		*f = ""
+	case "json":
+		*f = "json"
+	default:
+		return fmt.Errorf("want text or json")
+	}
+	return nil
+}
+
+func printSummary(s utils.Summary, asJSON bool) {
+	if asJSON {
+		enc := json.NewEncoder(os.Stderr)
+		enc.SetIndent("", "  ")
+		enc.Encode(s)
+		return
+	}
+	used := 0
+	for _, p := range s.Paths {
+		if p.Ants > 0 {
+			used++
+		}
+	}
+	w := os.Stderr
+	fmt.Fprintln(w, "turns:", s.Turns)
+	fmt.Fprintln(w, "moves:", s.Moves)
+	fmt.Fprintf(w, "average arrival: %.2f\n", s.AvgArrival)
+	fmt.Fprintln(w, "paths used:", used)
+	for i, p := range s.Paths {
+		fmt.Fprintf(w, "path %d: %s (length %d, %d ants)\n", i+1, strings.Join(p.Rooms, " "), p.Length, p.Ants)
+	}
+	arrivals := make([]string, len(s.Arrivals))
+	for i, a := range s.Arrivals {
+		arrivals[i] = strconv.Itoa(a)
+	}
+	fmt.Fprintln(w, "arrivals:", strings.Join(arrivals, " "))
+	fmt.Fprintln(w, "utilization:")
+	for _, u := range s.Utilization {
+		fmt.Fprintf(w, "  %s %d/%d\n", u.Room, u.Turns, s.Turns)
+	}
+}
+
+// printLateAnts lists on stderr the ants that miss their deadline.
+func printLateAnts(late []utils.LateAnt) {
+	for _, l := range late {
+		reason := ""
+		if l.Infeasible {
+			reason = ", which no schedule can meet"
+		}
+		fmt.Fprintf(os.Stderr, "deadline missed: L%s due by turn %d arrives at turn %d%s\n", l.Ant, l.Deadline, l.Arrival, reason)
+	}
+}
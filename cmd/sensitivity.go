@@ -0,0 +1,26 @@
+package main
+
+import (
+	"fmt"
+
+	"lem-in/utils"
+)
+
+func printSensitivity(g *utils.Graph) {
+	report := utils.LinkSensitivity(g)
+	if report == nil {
+		failNoPath()
+	}
+	fmt.Printf("%-24s %s\n", "tunnel", "extra turns")
+	for _, l := range report {
+		impact := fmt.Sprintf("+%d", l.ExtraTurns)
+		if l.Disconnects {
+			impact = "disconnects"
+		}
+		sep := "-"
+		if l.OneWay {
+			sep = "->"
+		}
+		fmt.Printf("%-24s %s\n", l.From+sep+l.To, impact)
+	}
+}
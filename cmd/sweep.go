@@ -0,0 +1,116 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+
+	"lem-in/utils"
+)
+
+const (
+	chartWidth  = 60
+	chartHeight = 16
+)
+
+// printSweep writes the turns-versus-ants curve as CSV on stdout and the
+// breakpoints and an ASCII chart on stderr.
+func printSweep(g *utils.Graph, antsRange string) {
+	from, to := 1, g.Ants
+	if antsRange != "" {
+		var err error
+		from, to, err = parseRange(antsRange)
+		if err != nil {
+			fmt.Println("ERROR: invalid ant range")
+			fmt.Println("Reason: " + err.Error())
+			os.Exit(1)
+		}
+	}
+	points, breaks, err := utils.Sweep(g, from, to)
+	if err != nil {
+		fail(err)
+	}
+	if points == nil {
+		failNoPath()
+	}
+
+	fmt.Println("ants,turns,paths,lengths")
+	for _, p := range points {
+		lengths := make([]string, len(p.Paths))
+		for i, path := range p.Paths {
+			lengths[i] = strconv.Itoa(utils.PathLength(path))
+		}
+		fmt.Printf("%d,%d,%d,%s\n", p.Ants, p.Turns, len(p.Paths), strings.Join(lengths, " "))
+	}
+
+	fmt.Fprintln(os.Stderr, "breakpoints:")
+	for _, p := range breaks {
+		fmt.Fprintf(os.Stderr, "  %d paths pay off from %d ants (%d turns)\n", len(p.Paths), p.Ants, p.Turns)
+	}
+	fmt.Fprintln(os.Stderr)
+	for _, line := range chart(points) {
+		fmt.Fprintln(os.Stderr, line)
+	}
+}
+
+// parseRange reads "from..to", or a single ant count.
+func parseRange(s string) (int, int, error) {
+	lo, hi, found := strings.Cut(s, "..")
+	if !found {
+		hi = lo
+	}
+	from, err1 := strconv.Atoi(lo)
+	to, err2 := strconv.Atoi(hi)
+	if err1 != nil || err2 != nil || from < 1 || to < from || to > utils.MaxAnts {
+		return 0, 0, fmt.Errorf("expected from..to with 1 <= from <= to <= %d", utils.MaxAnts)
+	}
+	return from, to, nil
+}
+
+// chart draws turns against ants, sampling one point per column.
+func chart(points []utils.SweepPoint) []string {
+	width := chartWidth
+	if len(points) < width {
+		width = len(points)
+	}
+	maxTurns := points[len(points)-1].Turns
+	minTurns := points[0].Turns
+	grid := make([][]byte, chartHeight)
+	for i := range grid {
+		grid[i] = []byte(strings.Repeat(" ", width))
+	}
+	for col := 0; col < width; col++ {
+		p := points[col*(len(points)-1)/max(width-1, 1)]
+		row := 0
+		if maxTurns > minTurns {
+			row = (p.Turns - minTurns) * (chartHeight - 1) / (maxTurns - minTurns)
+		}
+		grid[chartHeight-1-row][col] = '*'
+	}
+	lines := make([]string, 0, chartHeight+2)
+	for i, row := range grid {
+		label := ""
+		switch i {
+		case 0:
+			label = strconv.Itoa(maxTurns)
+		case chartHeight - 1:
+			label = strconv.Itoa(minTurns)
+		}
+		lines = append(lines, fmt.Sprintf("%8s |%s", label, row))
+	}
+	lines = append(lines, fmt.Sprintf("%8s +%s", "turns", strings.Repeat("-", width)))
+	lines = append(lines, fmt.Sprintf("%8s  %-*d%d ants", "", width-len(strconv.Itoa(points[len(points)-1].Ants)), points[0].Ants, points[len(points)-1].Ants))
+	return lines
+}
+
+func printMaxAnts(g *utils.Graph, turns int) {
+	ants, paths, err := utils.MaxAntsForTurns(g, turns)
+	if err != nil {
+		fail(err)
+	}
+	fmt.Printf("max ants in %d turns: %d\n", turns, ants)
+	for _, p := range paths {
+		fmt.Printf("  %s (%d)\n", formatPath(p), utils.PathLength(p))
+	}
+}
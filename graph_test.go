@@ -0,0 +1,62 @@
+package utils_test
+
+import (
+	"testing"
+
+	"lem-in/utils"
+)
+
+func TestGraphBuiltInCode(t *testing.T) {
+	g := utils.NewGraph(4)
+	for i, name := range []string{"0", "2", "3", "1"} {
+		if _, err := g.AddRoom(name, i, 0); err != nil {
+			t.Fatalf("add room %s: %v", name, err)
+		}
+	}
+	for _, l := range [][2]string{{"0", "2"}, {"2", "3"}, {"3", "1"}} {
+		if err := g.AddLink(l[0], l[1]); err != nil {
+			t.Fatalf("add link %v: %v", l, err)
+		}
+	}
+	if err := g.SetStart("0"); err != nil {
+		t.Fatal(err)
+	}
+	if err := g.SetEnd("1"); err != nil {
+		t.Fatal(err)
+	}
+	if err := g.Validate(); err != nil {
+		t.Fatalf("validate: %v", err)
+	}
+	if got := len(utils.FindPaths(g)); got != 1 {
+		t.Fatalf("got %d paths, want 1", got)
+	}
+
+	if _, err := g.AddRoom("L1", 9, 9); err == nil {
+		t.Error("expected invalid room name error")
+	}
+	if _, err := g.AddRoom("x", 0, 0); err == nil {
+		t.Error("expected duplicate coordinates error")
+	}
+	if err := g.AddLink("2", "2"); err == nil {
+		t.Error("expected self-loop error")
+	}
+	if err := g.AddLink("3", "2"); err == nil {
+		t.Error("expected duplicate link error")
+	}
+
+	if err := g.RemoveRoom("3"); err != nil {
+		t.Fatal(err)
+	}
+	if nb := g.Neighbors("2"); len(nb) != 1 || nb[0].Name != "0" {
+		t.Errorf("neighbors of 2 after removing 3: %v", nb)
+	}
+	if got := len(utils.FindPaths(g)); got != 0 {
+		t.Errorf("got %d paths after removing 3, want 0", got)
+	}
+	if _, err := g.AddRoom("x", 2, 0); err != nil {
+		t.Errorf("coordinates of removed room 3 still taken: %v", err)
+	}
+	if _, err := g.Clone().AddRoom("y", 2, 0); err == nil {
+		t.Error("clone lost the coordinates of room x")
+	}
+}
@@ -0,0 +1,154 @@
+package utils
+
+import "sort"
+
+// flowNet is a residual network stored as paired arcs: arc e and e^1 are
+// each other's reverse.
+type flowNet struct {
+	head []int
+	to   []int
+	cap  []int
+	next []int
+}
+
+func newFlowNet(n int) *flowNet {
+	head := make([]int, n)
+	for i := range head {
+		head[i] = -1
+	}
+	return &flowNet{head: head}
+}
+
+func (f *flowNet) addEdge(u, v, c int) int {
+	e := len(f.to)
+	f.to = append(f.to, v, u)
+	f.cap = append(f.cap, c, 0)
+	f.next = append(f.next, f.head[u], f.head[v])
+	f.head[u] = e
+	f.head[v] = e + 1
+	return e
+}
+
+// maxFlow pushes augmenting paths from s to t until none remain or limit
+// units have been sent.
+func (f *flowNet) maxFlow(s, t, limit int) int {
+	flow := 0
+	prev := make([]int, len(f.head))
+	for flow < limit {
+		for i := range prev {
+			prev[i] = -1
+		}
+		prev[s] = -2
+		queue := []int{s}
+		for len(queue) > 0 && prev[t] == -1 {
+			u := queue[0]
+			queue = queue[1:]
+			for e := f.head[u]; e != -1; e = f.next[e] {
+				if f.cap[e] > 0 && prev[f.to[e]] == -1 {
+					prev[f.to[e]] = e
+					queue = append(queue, f.to[e])
+				}
+			}
+		}
+		if prev[t] == -1 {
+			break
+		}
+		push := limit - flow
+		for v := t; v != s; v = f.to[prev[v]^1] {
+			if f.cap[prev[v]] < push {
+				push = f.cap[prev[v]]
+			}
+		}
+		for v := t; v != s; v = f.to[prev[v]^1] {
+			f.cap[prev[v]] -= push
+			f.cap[prev[v]^1] += push
+		}
+		flow += push
+	}
+	return flow
+}
+
+// reachable marks the nodes reachable from s in the residual network.
+func (f *flowNet) reachable(s int) []bool {
+	seen := make([]bool, len(f.head))
+	seen[s] = true
+	stack := []int{s}
+	for len(stack) > 0 {
+		u := stack[len(stack)-1]
+		stack = stack[:len(stack)-1]
+		for e := f.head[u]; e != -1; e = f.next[e] {
+			if f.cap[e] > 0 && !seen[f.to[e]] {
+				seen[f.to[e]] = true
+				stack = append(stack, f.to[e])
+			}
+		}
+	}
+	return seen
+}
+
+// roomIndex numbers the rooms in name order so that results built on it
+// are deterministic.
+func roomIndex(g *Graph) ([]*Room, map[*Room]int) {
+	rooms := make([]*Room, 0, len(g.Rooms))
+	for _, r := range g.Rooms {
+		rooms = append(rooms, r)
+	}
+	sort.Slice(rooms, func(i, j int) bool { return rooms[i].Name < rooms[j].Name })
+	idx := make(map[*Room]int, len(rooms))
+	for i, r := range rooms {
+		idx[r] = i
+	}
+	return rooms, idx
+}
+
+// vertexNet splits every room i into an in node 2*i and an out node 2*i+1
+// joined by an arc carrying the room's capacity, one for ordinary rooms,
+// so that flows respect how many paths each room admits. Tunnels become
+// unbounded arcs in each direction they can be crossed so that minimum
+// cuts consist of rooms, except that a tunnel joining two rooms that each
+// admit several paths carries only as many as its width. The returned
+// slice holds the index of each room's inner arc. Two
+// extra nodes are allocated after the rooms for callers that need a super
+// source or sink.
+func vertexNet(g *Graph, rooms []*Room, idx map[*Room]int) (*flowNet, []int) {
+	f := newFlowNet(2*len(rooms) + 2)
+	inner := make([]int, len(rooms))
+	capacity := func(r *Room) int {
+		if g.isStart(r) || g.isEnd(r) {
+			return len(rooms)
+		}
+		return r.capacity()
+	}
+	for i, r := range rooms {
+		inner[i] = f.addEdge(2*i, 2*i+1, capacity(r))
+	}
+	for i, r := range rooms {
+		for _, nb := range r.Links {
+			c := len(rooms)
+			if capacity(r) > 1 && capacity(nb) > 1 {
+				c = r.LinkWidth(nb)
+			}
+			f.addEdge(2*i+1, 2*idx[nb], c)
+		}
+	}
+	return f, inner
+}
+
+// MaxDisjointPaths returns the number of vertex-disjoint paths from start
+// to end, the upper bound on how many paths FindPaths can use at once.
+// Several start and end rooms are joined to a super source and sink.
+func MaxDisjointPaths(g *Graph) int {
+	if g.Start == nil || g.End == nil {
+		return 0
+	}
+	rooms, idx := roomIndex(g)
+	f, _ := vertexNet(g, rooms, idx)
+	src, sink := 2*len(rooms), 2*len(rooms)+1
+	for _, r := range g.starts() {
+		f.addEdge(src, 2*idx[r]+1, len(rooms))
+	}
+	for _, r := range g.ends() {
+		f.addEdge(2*idx[r], sink, len(rooms))
+	}
+	return f.maxFlow(src, sink, len(rooms))
+}
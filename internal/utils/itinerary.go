@@ -0,0 +1,24 @@
+package utils
+
+// Visit records the turn at which an ant entered a room; every ant is in
+// the start room at turn 0.
+type Visit struct {
+	Room *Room
+	Turn int
+}
+
+// Itineraries simulates the ants along paths, distributed for obj, and
+// returns the rooms each ant visits, ant 1 first.
+func Itineraries(g *Graph, paths [][]*Room, obj Objective) [][]Visit {
+	route := routeAnts(g, paths, obj)
+	res := make([][]Visit, len(route))
+	for i := range res {
+		res[i] = []Visit{{Room: paths[route[i]][0]}}
+	}
+	for t, turn := range simulate(g, paths, route) {
+		for _, m := range turn {
+			res[m.Ant-1] = append(res[m.Ant-1], Visit{Room: m.Room, Turn: t + 1})
+		}
+	}
+	return res
+}
@@ -1,43 +1,172 @@
 package utils
 
 import (
-
 	"bufio"
+	"math"
 	"os"
 	"strconv"
 	"strings"
 )
 
+// linkLine is a link read from the input, resolved once all rooms are known.
+type linkLine struct {
+	a, b          string
+	length, width int
+	oneway        bool
+	closed        []Window
+}
+
+// isLinkLine reports whether fields form a link, "a-b" or the one-way
+// "a->b", optionally followed by a length and an "xN" width. Room names
+// may hold a ">" but no "-". A line that also reads as a room, "a-b 1 2",
+// is a room.
+func isLinkLine(fields []string) bool {
+	if len(fields) == 0 || len(fields) > 3 || strings.Count(fields[0], "-") != 1 {
+		return false
+	}
+	if len(fields) == 3 {
+		_, err1 := strconv.Atoi(fields[1])
+		_, err2 := strconv.Atoi(fields[2])
+		return err1 != nil || err2 != nil
+	}
+	return true
+}
+
+// ParseOptions enables extensions of the input format.
+type ParseOptions struct {
+	// MultiTerminal allows several ##start and ##end rooms. "##start N"
+	// puts N of the ants in that start room; at most one start room may
+	// omit its count and receives the remaining ants.
+	MultiTerminal bool
+	// Colonies allows several groups of ants, each given a start room by
+	// "##start NAME N" and an end room by "##end NAME". NAME is made of
+	// letters; at most one colony may omit its count and receives the
+	// remaining ants. A room may be the start of one colony and the end
+	// of another, for groups travelling in opposite directions.
+	Colonies bool
+	// Euclid derives the length of every tunnel from the distance between
+	// its rooms, rounded up to whole turns, unless ##length gives it.
+	Euclid bool
+}
+
+// colonyLine is a colony read from the input.
+type colonyLine struct {
+	name       string
+	start, end *Room
+	ants       int
+}
+
 func ParseInput(path string) (*Graph, []string, error) {
+	return ParseInputWith(path, ParseOptions{})
+}
+
+// ParseInputWith is ParseInput accepting the extensions enabled in opts.
+func ParseInputWith(path string, opts ParseOptions) (*Graph, []string, error) {
 	file, err := os.Open(path)
 	if err != nil {
 		return nil, nil, err
 	}
 	defer file.Close()
 
-	g := &Graph{Rooms: make(map[string]*Room)}
+	g := NewGraph(0)
+	g.Euclid = opts.Euclid
 	scanner := bufio.NewScanner(file)
 	var lines []string
 	var pendingStart, pendingEnd bool
-	var links [][2]string
+	pendingStartAnts := -1
+	var starts []*Room
+	var startAnts []int
+	var ends []*Room
+	var colonies []*colonyLine
+	var pendingColonyStart, pendingColonyEnd *colonyLine
+	var links []linkLine
+	pendingLength, pendingCapacity := 0, 0
+	pendingOneWay, pendingCheckpoint := false, false
+	var pendingClosed []Window
+	var speeds []SpeedClass
+	var deadlines []Deadline
 	parsedAnts := false
-	coords := map[[2]int]bool{}
 	linkSeen := map[string]struct{}{}
 
 	for scanner.Scan() {
 		line := scanner.Text()
 		lines = append(lines, line)
 		if strings.HasPrefix(line, "#") {
-			if line == "##start" {
-				if pendingStart || g.Start != nil {
+			if f := strings.Fields(line); opts.Colonies && len(f) > 1 && (f[0] == "##start" || f[0] == "##end") {
+				if f[0] == "##start" && pendingColonyStart != nil || f[0] == "##end" && pendingColonyEnd != nil {
+					return nil, lines, LemError{"ERROR: invalid data format", "two colony " + f[0][2:] + "s before a room"}
+				}
+				if len(f) > 3 || f[0] == "##end" && len(f) > 2 || !validColonyName(f[1]) {
+					return nil, lines, LemError{"ERROR: invalid data format", "invalid colony directive '" + line + "'"}
+				}
+				var c *colonyLine
+				for _, o := range colonies {
+					if o.name == f[1] {
+						c = o
+					}
+				}
+				if c == nil {
+					c = &colonyLine{name: f[1], ants: -1}
+					colonies = append(colonies, c)
+				}
+				if f[0] == "##start" && len(f) == 3 {
+					n, err := strconv.Atoi(f[2])
+					if err != nil || n < 0 {
+						return nil, lines, LemError{"ERROR: invalid data format", "invalid ants count for colony '" + c.name + "'"}
+					}
+					c.ants = n
+				}
+				if f[0] == "##start" && c.start != nil || f[0] == "##end" && c.end != nil {
+					return nil, lines, LemError{"ERROR: invalid data format", "duplicate " + f[0][2:] + " for colony '" + c.name + "'"}
+				}
+				if f[0] == "##start" {
+					pendingColonyStart = c
+				} else {
+					pendingColonyEnd = c
+				}
+			} else if line == "##start" || opts.MultiTerminal && strings.HasPrefix(line, "##start ") {
+				if pendingStart || g.Start != nil && !opts.MultiTerminal {
 					return nil, lines, LemError{"ERROR: invalid data format", "duplicate start"}
 				}
 				pendingStart = true
+				pendingStartAnts = -1
+				if arg, ok := strings.CutPrefix(line, "##start "); ok {
+					n, err := strconv.Atoi(strings.TrimSpace(arg))
+					if err != nil || n < 0 {
+						return nil, lines, LemError{"ERROR: invalid data format", "invalid start ants count '" + arg + "'"}
+					}
+					pendingStartAnts = n
+				}
 			} else if line == "##end" {
-				if pendingEnd || g.End != nil {
+				if pendingEnd || g.End != nil && !opts.MultiTerminal {
 					return nil, lines, LemError{"ERROR: invalid data format", "duplicate end"}
 				}
 				pendingEnd = true
+			} else if n, ok := countArg(line, "##length"); ok {
+				pendingLength = n
+			} else if n, ok := countArg(line, "##capacity"); ok {
+				pendingCapacity = n
+			} else if f := strings.Fields(line); f[0] == "##speed" || f[0] == "##deadline" {
+				n, k, ok := parseAntGroup(f[1:])
+				if !ok {
+					continue
+				}
+				if !parsedAnts || len(g.Rooms) > 0 {
+					return nil, lines, LemError{"ERROR: invalid data format", f[0] + " must follow the ants count"}
+				}
+				if f[0] == "##speed" {
+					speeds = append(speeds, SpeedClass{Every: n, Ants: k})
+				} else {
+					deadlines = append(deadlines, Deadline{Turn: n, Ants: k})
+				}
+			} else if line == "##oneway" {
+				pendingOneWay = true
+			} else if line == "##checkpoint" {
+				pendingCheckpoint = true
+			} else if line == "##closed" || strings.HasPrefix(line, "##closed ") {
+				if w, ok := parseWindow(strings.TrimSpace(strings.TrimPrefix(line, "##closed"))); ok {
+					pendingClosed = append(pendingClosed, w)
+				}
 			}
 			continue
 		}
@@ -56,51 +185,84 @@ func ParseInput(path string) (*Graph, []string, error) {
 		}
 
 		fields := strings.Fields(line)
-		if len(fields) == 3 {
-			name := fields[0]
-			if strings.HasPrefix(name, "L") || strings.HasPrefix(name, "#") {
-				return nil, lines, LemError{"ERROR: invalid data format", "invalid room name '" + name + "'"}
-			}
-			if _, ok := g.Rooms[name]; ok {
-				return nil, lines, LemError{"ERROR: invalid data format", "duplicate room name '" + name + "'"}
-			}
+		if len(fields) == 3 && !isLinkLine(fields) {
 			x, err1 := strconv.Atoi(fields[1])
 			y, err2 := strconv.Atoi(fields[2])
 			if err1 != nil || err2 != nil {
 				return nil, lines, LemError{"ERROR: invalid data format", "invalid room line"}
 			}
-			if coords[[2]int{x, y}] {
-				return nil, lines, LemError{"ERROR: invalid data format", "duplicate coordinates " + fields[1] + " " + fields[2]}
+			r, err := g.AddRoom(fields[0], x, y)
+			if err != nil {
+				return nil, lines, err
+			}
+			r.Capacity = pendingCapacity
+			pendingCapacity = 0
+			if pendingCheckpoint {
+				g.Checkpoints = append(g.Checkpoints, r)
+				pendingCheckpoint = false
+			}
+			r.Closed, pendingClosed = pendingClosed, nil
+			if pendingColonyStart != nil || pendingColonyEnd != nil {
+				if pendingColonyStart != nil {
+					pendingColonyStart.start = r
+				}
+				if pendingColonyEnd != nil {
+					pendingColonyEnd.end = r
+				}
+				pendingColonyStart, pendingColonyEnd = nil, nil
+				continue
 			}
-			coords[[2]int{x, y}] = true
-			r := &Room{Name: name, X: x, Y: y}
-			g.Rooms[name] = r
 			if pendingStart {
-				g.Start = r
+				if g.Start == nil {
+					g.Start = r
+				}
+				starts = append(starts, r)
+				startAnts = append(startAnts, pendingStartAnts)
 				pendingStart = false
 			}
 			if pendingEnd {
-				g.End = r
+				if g.End == nil {
+					g.End = r
+				}
+				ends = append(ends, r)
 				pendingEnd = false
 			}
 			continue
 		}
 
-		if strings.Count(line, "-") == 1 && !strings.Contains(line, " ") {
-			parts := strings.Split(line, "-")
+		if isLinkLine(fields) {
+			l := linkLine{length: pendingLength, oneway: pendingOneWay, closed: pendingClosed}
+			for _, opt := range fields[1:] {
+				if w, ok := strings.CutPrefix(opt, "x"); ok {
+					n, err := strconv.Atoi(w)
+					if err != nil || n < 1 {
+						return nil, lines, LemError{"ERROR: invalid data format", "invalid tunnel width '" + opt + "'"}
+					}
+					l.width = n
+					continue
+				}
+				n, err := strconv.Atoi(opt)
+				if err != nil || n < 1 {
+					return nil, lines, LemError{"ERROR: invalid data format", "invalid tunnel length '" + opt + "'"}
+				}
+				l.length = n
+			}
+			parts := strings.Split(fields[0], "-")
+			if b, ok := strings.CutPrefix(parts[1], ">"); ok {
+				parts[1] = b
+				l.oneway = true
+			}
 			if parts[0] == parts[1] {
 				return nil, lines, LemError{"ERROR: invalid data format", "self-loop link " + parts[0] + "-" + parts[1]}
 			}
-			a, b := parts[0], parts[1]
-			if b < a {
-				a, b = b, a
-			}
-			key := a + "-" + b
+			key := linkKey(parts[0], parts[1])
 			if _, ok := linkSeen[key]; ok {
 				return nil, lines, LemError{"ERROR: invalid data format", "duplicate link " + key}
 			}
 			linkSeen[key] = struct{}{}
-			links = append(links, [2]string{parts[0], parts[1]})
+			l.a, l.b = parts[0], parts[1]
+			links = append(links, l)
+			pendingLength, pendingOneWay, pendingClosed = 0, false, nil
 			continue
 		}
 
@@ -110,26 +272,181 @@ func ParseInput(path string) (*Graph, []string, error) {
 		return nil, lines, err
 	}
 
+	if len(colonies) > 0 {
+		if err := setColonies(g, colonies); err != nil {
+			return nil, lines, err
+		}
+	}
 	if g.Start == nil || g.End == nil {
 		return nil, lines, LemError{"ERROR: invalid data format", "missing start or end"}
 	}
+	if opts.MultiTerminal && len(colonies) == 0 {
+		if err := setTerminals(g, starts, startAnts, ends); err != nil {
+			return nil, lines, err
+		}
+	}
+	for _, c := range speeds {
+		if err := g.AddSpeedClass(c.Every, c.Ants); err != nil {
+			return nil, lines, err
+		}
+	}
+	for _, d := range deadlines {
+		if err := g.AddDeadline(d.Turn, d.Ants); err != nil {
+			return nil, lines, err
+		}
+	}
+	for _, r := range g.Checkpoints {
+		if g.isStart(r) || g.isEnd(r) {
+			return nil, lines, LemError{"ERROR: invalid data format", "checkpoint '" + r.Name + "' is a start or end room"}
+		}
+	}
 
 	for _, l := range links {
-		a, ok1 := g.Rooms[l[0]]
-		b, ok2 := g.Rooms[l[1]]
-		if !ok1 || !ok2 {
-			return nil, lines, LemError{"ERROR: invalid data format", "unknown room in link '" + l[0] + "'"}
+		if err := g.AddLink(l.a, l.b); err != nil {
+			return nil, lines, err
+		}
+		if l.length > 0 {
+			if err := g.SetLength(l.a, l.b, l.length); err != nil {
+				return nil, lines, err
+			}
+		}
+		if l.width > 1 {
+			if err := g.SetWidth(l.a, l.b, l.width); err != nil {
+				return nil, lines, err
+			}
 		}
-		if !hasNeighbor(a, b) {
-			a.Links = append(a.Links, b)
+		if l.oneway {
+			if err := g.SetOneWay(l.a, l.b); err != nil {
+				return nil, lines, err
+			}
 		}
-		if !hasNeighbor(b, a) {
-			b.Links = append(b.Links, a)
+		for _, w := range l.closed {
+			if err := g.CloseLink(l.a, l.b, w); err != nil {
+				return nil, lines, err
+			}
 		}
 	}
 	return g, lines, nil
 }
 
+// countArg reads the positive count of a directive such as "##length 3".
+// It reports false when line is not that directive with a valid count,
+// so that the line is an ordinary comment.
+func countArg(line, directive string) (int, bool) {
+	arg, ok := strings.CutPrefix(line, directive+" ")
+	if !ok {
+		return 0, false
+	}
+	n, err := strconv.Atoi(strings.TrimSpace(arg))
+	return n, err == nil && n >= 1
+}
+
+// parseAntGroup reads the two positive numbers of a ##speed or
+// ##deadline directive: the turns, then the ants they apply to.
+func parseAntGroup(f []string) (int, int, bool) {
+	if len(f) != 2 {
+		return 0, 0, false
+	}
+	n, err1 := strconv.Atoi(f[0])
+	k, err2 := strconv.Atoi(f[1])
+	return n, k, err1 == nil && err2 == nil && n >= 1 && k >= 1
+}
+
+// parseWindow reads the turns of a ##closed directive: "3-7", a single
+// turn "5", an open range "3-" or nothing for every turn.
+func parseWindow(arg string) (Window, bool) {
+	if arg == "" {
+		return Window{1, math.MaxInt}, true
+	}
+	from, to, isRange := strings.Cut(arg, "-")
+	a, err := strconv.Atoi(from)
+	if err != nil || a < 1 {
+		return Window{}, false
+	}
+	if !isRange {
+		return Window{a, a}, true
+	}
+	if to == "" {
+		return Window{a, math.MaxInt}, true
+	}
+	b, err := strconv.Atoi(to)
+	if err != nil || b < a {
+		return Window{}, false
+	}
+	return Window{a, b}, true
+}
+
+// setTerminals records the start and end rooms, giving the ants not placed
+// by a "##start N" directive to the start room without one. A colony with
+// a single start and end is left a classic one.
+func setTerminals(g *Graph, starts []*Room, ants []int, ends []*Room) error {
+	if err := fillAnts(g.Ants, ants, "start"); err != nil {
+		return err
+	}
+	if len(starts) == 1 && len(ends) == 1 {
+		return nil
+	}
+	g.Start, g.End = nil, nil
+	for i, r := range starts {
+		if err := g.AddStart(r.Name, ants[i]); err != nil {
+			return err
+		}
+	}
+	for _, r := range ends {
+		if err := g.AddEnd(r.Name); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// setColonies adds the colonies read from the input, giving the ants not
+// placed by a count to the colony without one.
+func setColonies(g *Graph, colonies []*colonyLine) error {
+	if g.Start != nil || g.End != nil {
+		return LemError{"ERROR: invalid data format", "start or end outside a colony"}
+	}
+	ants := make([]int, len(colonies))
+	for i, c := range colonies {
+		if c.start == nil || c.end == nil {
+			return LemError{"ERROR: invalid data format", "missing start or end for colony '" + c.name + "'"}
+		}
+		ants[i] = c.ants
+	}
+	if err := fillAnts(g.Ants, ants, "colony"); err != nil {
+		return err
+	}
+	for i, c := range colonies {
+		if err := g.AddColony(c.name, c.start.Name, c.end.Name, ants[i]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// fillAnts gives the ants not placed by an explicit count, the entries of
+// ants that are -1, to the only such entry and checks that the counts add
+// up to total. kind names what the counts belong to in errors.
+func fillAnts(total int, ants []int, kind string) error {
+	rest, open := total, -1
+	for i, n := range ants {
+		if n >= 0 {
+			rest -= n
+		} else if open >= 0 {
+			return LemError{"ERROR: invalid data format", "more than one " + kind + " without an ants count"}
+		} else {
+			open = i
+		}
+	}
+	if open >= 0 && rest >= 0 {
+		ants[open], rest = rest, 0
+	}
+	if rest != 0 {
+		return LemError{"ERROR: invalid data format", kind + " ant counts do not add up to " + strconv.Itoa(total)}
+	}
+	return nil
+}
+
 func hasNeighbor(r, other *Room) bool {
 	for _, nb := range r.Links {
 		if nb == other {
@@ -137,5 +454,4 @@ func hasNeighbor(r, other *Room) bool {
 		}
 	}
 	return false
-
 }
@@ -0,0 +1,100 @@
+package utils
+
+import (
+	"runtime"
+	"sort"
+	"sync"
+)
+
+// LinkImpact is the cost of losing one tunnel.
+type LinkImpact struct {
+	From string
+	To   string
+	// OneWay is set when the tunnel only leads from From to To.
+	OneWay      bool
+	ExtraTurns  int
+	Disconnects bool
+}
+
+// LinkSensitivity removes each tunnel in turn and reports how many extra
+// turns the best solution then needs, most harmful first. Tunnels unused
+// by the current solution are known to cost nothing and are not solved
+// again; the rest are solved in parallel, each worker editing its own
+// copy of the colony. It returns nil when start and end are not
+// connected at all.
+func LinkSensitivity(g *Graph) []LinkImpact {
+	paths := FindPaths(g)
+	if len(paths) == 0 {
+		return nil
+	}
+	base := PathTurns(g.Ants, paths)
+	used := map[string]bool{}
+	for _, p := range paths {
+		for i := 1; i < len(p); i++ {
+			used[linkKey(p[i-1].Name, p[i].Name)] = true
+		}
+	}
+
+	rooms, _ := roomIndex(g)
+	var res []LinkImpact
+	var todo []int
+	for _, a := range rooms {
+		for _, b := range a.Links {
+			// A two-way tunnel is listed on both rooms; report it once.
+			if a.Name >= b.Name && hasNeighbor(b, a) {
+				continue
+			}
+			if used[linkKey(a.Name, b.Name)] {
+				todo = append(todo, len(res))
+			}
+			res = append(res, LinkImpact{From: a.Name, To: b.Name, OneWay: !hasNeighbor(b, a)})
+		}
+	}
+
+	jobs := make(chan int)
+	var wg sync.WaitGroup
+	for w := 0; w < runtime.NumCPU() && w < len(todo); w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			work := g.Clone()
+			for i := range jobs {
+				l := &res[i]
+				a, b := work.Rooms[l.From], work.Rooms[l.To]
+				la := append([]*Room{}, a.Links...)
+				lb := append([]*Room{}, b.Links...)
+				ta, okA := a.Tunnels[b]
+				tb, okB := b.Tunnels[a]
+				work.RemoveLink(l.From, l.To)
+				if _, ok := distances(work.Start)[work.End]; !ok {
+					l.Disconnects = true
+				} else {
+					l.ExtraTurns = PathTurns(work.Ants, FindPaths(work)) - base
+				}
+				// Restore the original neighbour order, which FindPaths
+				// depends on for tie-breaking, and the tunnel's length,
+				// width and closures.
+				a.Links, b.Links = la, lb
+				if okA {
+					a.Tunnels[b] = ta
+				}
+				if okB {
+					b.Tunnels[a] = tb
+				}
+			}
+		}()
+	}
+	for _, i := range todo {
+		jobs <- i
+	}
+	close(jobs)
+	wg.Wait()
+
+	sort.SliceStable(res, func(i, j int) bool {
+		if res[i].Disconnects != res[j].Disconnects {
+			return res[i].Disconnects
+		}
+		return res[i].ExtraTurns > res[j].ExtraTurns
+	})
+	return res
+}
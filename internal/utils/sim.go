@@ -1,8 +1,9 @@
 package utils
 
 import (
-
-	"fmt"
+	"maps"
+	"math"
+	"slices"
 	"sort"
 	"strings"
 )
@@ -14,73 +15,441 @@ type antState struct {
 }
 
 func SimulateMulti(g *Graph, paths [][]*Room) []string {
+	return SimulateWith(g, paths, ObjectiveNone)
+}
+
+// SimulateWith is SimulateMulti distributing ants over paths to suit obj.
+// A turn in which no ant arrives in a room is an empty string.
+func SimulateWith(g *Graph, paths [][]*Room, obj Objective) []string {
 	if len(paths) == 0 {
 		return nil
 	}
-	route := assignPaths(paths, g.Ants)
-	queues := make([][]int, len(paths))
-	for ant, p := range route {
-		queues[p] = append(queues[p], ant)
-	}
-	pos := make([]int, len(route))
-	started := make([]bool, len(route))
-	occupancy := map[*Room]int{}
-	finished := 0
 	var moves []string
-	for finished < len(route) {
-		type evt struct {
-			id   int
-			room *Room
-		}
-		var evts []evt
-		for id := 0; id < len(route); id++ {
-			if !started[id] {
-				continue
+	for _, turn := range simulate(g, paths, routeAnts(g, paths, obj)) {
+		moves = append(moves, formatMoves(g, turn))
+	}
+	return moves
+}
+
+// ArrivalTurns drops the empty turns from moves, in which no ant arrives
+// in a room, as when ants are all inside long tunnels. The turns left no
+// longer tell when the ants arrive, but none of them reads as an empty
+// line.
+func ArrivalTurns(moves []string) []string {
+	return slices.DeleteFunc(slices.Clone(moves), func(m string) bool { return m == "" })
+}
+
+// formatMoves prints the moves of a turn as "L1-a L2-b".
+func formatMoves(g *Graph, turn []Move) string {
+	line := make([]string, len(turn))
+	for i, m := range turn {
+		line[i] = "L" + g.AntLabel(m.Ant) + "-" + m.Room.Name
+	}
+	return strings.Join(line, " ")
+}
+
+// Move is an ant, numbered from 1, entering a room.
+type Move struct {
+	Ant  int
+	Room *Room
+}
+
+// Snapshot is the state of a simulation after a turn.
+type Snapshot struct {
+	Turn int
+	// Ants holds the room of each ant, ant 1 first. An ant inside a long
+	// tunnel is counted in the room it left.
+	Ants []*Room
+	// InTunnel holds, for each ant crossing a long tunnel, the room it is
+	// heading to, and nil for the other ants.
+	InTunnel []*Room
+	// Rooms maps every occupied room other than start and end to its
+	// ants, in increasing order.
+	Rooms map[*Room][]int
+	// Waiting lists, per path, the ants still at start queued for it.
+	Waiting [][]int
+	// Finished lists the ants that have reached end, in arrival order.
+	Finished []int
+	// Moves are the moves made during this turn.
+	Moves []Move
+}
+
+// step is one position along a path: a room, or the k-th turn inside the
+// tunnel from one room to the next when room is nil.
+type step struct {
+	room     *Room
+	from, to *Room
+	k        int
+}
+
+// Simulation moves ants turn by turn along fixed paths. Every path sends
+// at most one ant per turn and an ant only enters a room once it is below
+// its capacity, or advances inside a long tunnel once the place ahead is
+// empty. A slow ant moves at most once every so many turns, its first
+// move included.
+type Simulation struct {
+	g         *Graph
+	paths     [][]*Room
+	steps     [][]step
+	route     []int
+	queues    [][]int
+	pos       []int
+	started   []bool
+	moving    []int            // started ants not yet at end, in increasing order
+	moves     int              // moves made so far, inside tunnels included
+	speed     []int            // turns each ant takes per move
+	ready     []int            // first turn at which each ant may move again
+	occupancy map[step]int     // ants on each step
+	entered   map[[2]*Room]int // ants that entered each tunnel this turn
+	finished  []int
+	turn      int
+	last      []Move
+	first     []int  // index of the first copy of each path
+	zones     []zone // stretches shared by paths going opposite ways
+	inZone    []int  // ants inside each zone
+	idle      int    // turns in a row in which no ant moved
+	calm      int    // first turn from which no closure starts or ends
+	slowest   int    // most turns any ant takes per move
+	passed    []int  // checkpoints each ant had passed when last rerouted
+}
+
+// zone is the stretch of steps lo to hi of a path, and of its copies,
+// that it shares with a path going the opposite way, whose own stretch is
+// zone opp. Ants enter a zone only while its opposite zone is empty.
+type zone struct {
+	path, lo, hi, opp int
+}
+
+// NewSimulation prepares g.Ants ants to travel along paths, distributed
+// to suit obj.
+func NewSimulation(g *Graph, paths [][]*Room, obj Objective) *Simulation {
+	return newSimulation(g, paths, routeAnts(g, paths, obj))
+}
+
+// newSimulation sends ant i+1 along paths[route[i]].
+func newSimulation(g *Graph, paths [][]*Room, route []int) *Simulation {
+	s := &Simulation{
+		g:         g,
+		paths:     paths,
+		steps:     make([][]step, len(paths)),
+		route:     route,
+		queues:    make([][]int, len(paths)),
+		pos:       make([]int, len(route)),
+		started:   make([]bool, len(route)),
+		speed:     g.antSpeeds(),
+		occupancy: map[step]int{},
+	}
+	if s.speed == nil {
+		s.speed = slices.Repeat([]int{1}, len(route))
+	}
+	s.ready = slices.Clone(s.speed)
+	s.slowest = 1
+	for _, n := range s.speed {
+		s.slowest = max(s.slowest, n)
+	}
+	for i, p := range paths {
+		s.steps[i] = stepsOf(p)
+	}
+	s.first = firstCopies(paths)
+	s.zones = zonesOf(paths, s.steps, s.first)
+	s.inZone = make([]int, len(s.zones))
+	for _, r := range g.Rooms {
+		windows := slices.Clone(r.Closed)
+		for _, t := range r.Tunnels {
+			windows = append(windows, t.Closed...)
+		}
+		for _, w := range windows {
+			s.calm = max(s.calm, w.From)
+			if w.To != math.MaxInt {
+				s.calm = max(s.calm, w.To+1)
 			}
-			p := paths[route[id]]
-			if pos[id] < len(p)-1 {
-				next := p[pos[id]+1]
-				if next == g.End || occupancy[next] == 0 {
-					if p[pos[id]] != g.Start {
-						delete(occupancy, p[pos[id]])
-					}
-					pos[id]++
-					if next != g.End {
-						occupancy[next] = id + 1
-					} else {
-						finished++
-					}
-					evts = append(evts, evt{id: id + 1, room: next})
-				}
+		}
+	}
+	for ant, p := range route {
+		s.queues[p] = append(s.queues[p], ant)
+	}
+	return s
+}
+
+// stepsOf expands p into the rooms and long tunnel sections it is made of.
+func stepsOf(p []*Room) []step {
+	steps := []step{{room: p[0]}}
+	for j := 1; j < len(p); j++ {
+		for k := 1; k < p[j-1].LinkLength(p[j]); k++ {
+			steps = append(steps, step{from: p[j-1], to: p[j], k: k})
+		}
+		steps = append(steps, step{room: p[j]})
+	}
+	return steps
+}
+
+// Done reports whether every ant has reached end.
+func (s *Simulation) Done() bool {
+	return len(s.finished) == len(s.route)
+}
+
+// Step plays one turn and returns the ants that entered a room, ordered
+// by ant.
+func (s *Simulation) Step() []Move {
+	var evts []Move
+	moves := s.moves
+	s.entered = map[[2]*Room]int{}
+	var moving []int
+	for _, id := range s.moving {
+		st := s.steps[s.route[id]]
+		if s.pos[id] < len(st)-1 && s.ready[id] <= s.turn+1 && s.canMove(st[s.pos[id]], st[s.pos[id]+1]) && s.zoneOpen(s.route[id], s.pos[id]+1) {
+			s.leave(st[s.pos[id]])
+			s.pos[id]++
+			s.ready[id] = s.turn + 1 + s.speed[id]
+			evts = s.enter(evts, id, st[s.pos[id]-1], st[s.pos[id]])
+		}
+		if s.pos[id] < len(st)-1 {
+			moving = append(moving, id)
+		}
+	}
+	for i, q := range s.queues {
+		if len(q) == 0 {
+			continue
+		}
+		ant := q[0]
+		if cur, next := s.steps[i][0], s.steps[i][1]; s.ready[ant] <= s.turn+1 && s.canMove(cur, next) && s.zoneOpen(i, 1) {
+			s.started[ant] = true
+			s.pos[ant] = 1
+			s.ready[ant] = s.turn + 1 + s.speed[ant]
+			s.queues[i] = q[1:]
+			evts = s.enter(evts, ant, cur, next)
+			if len(s.steps[i]) > 2 {
+				moving = append(moving, ant)
 			}
 		}
-		for i, q := range queues {
-			if len(q) == 0 {
+	}
+	slices.Sort(moving)
+	s.moving = moving
+	sort.Slice(evts, func(i, j int) bool { return evts[i].Ant < evts[j].Ant })
+	s.turn++
+	s.last = evts
+	if s.moves == moves {
+		s.idle++
+	} else {
+		s.idle = 0
+	}
+	return evts
+}
+
+// stuck reports whether the ants wait on each other for good: none moved
+// for longer than the slowest ant takes and no closure is left to change.
+func (s *Simulation) stuck() bool {
+	return !s.Done() && s.turn >= s.calm && s.idle > s.slowest
+}
+
+// zoneOpen reports whether an ant of path may move onto its step k,
+// which it may not when that enters a zone whose opposite one holds ants.
+func (s *Simulation) zoneOpen(path, k int) bool {
+	for _, z := range s.zones {
+		if z.path == s.first[path] && z.lo == k && s.inZone[z.opp] > 0 {
+			return false
+		}
+	}
+	return true
+}
+
+// zonesOf finds the zones of every two paths that oppose each other,
+// first telling which paths are copies of an earlier one.
+func zonesOf(paths [][]*Room, steps [][]step, first []int) []zone {
+	var zones []zone
+	var distinct []int
+	for i, f := range first {
+		if f == i {
+			distinct = append(distinct, i)
+		}
+	}
+	for x, i := range distinct {
+		for _, j := range distinct[x+1:] {
+			if !opposed(paths[i], paths[j]) {
 				continue
 			}
-			ant := q[0]
-			next := paths[i][1]
-			if next == g.End || occupancy[next] == 0 {
-				started[ant] = true
-				pos[ant] = 1
-				if next != g.End {
-					occupancy[next] = ant + 1
-				} else {
-					finished++
-				}
-				queues[i] = q[1:]
-				evts = append(evts, evt{id: ant + 1, room: next})
-			}
+			a, b := stretch(paths[i], paths[j], steps[i]), stretch(paths[j], paths[i], steps[j])
+			a.path, a.opp = i, len(zones)+1
+			b.path, b.opp = j, len(zones)
+			zones = append(zones, a, b)
+		}
+	}
+	return zones
+}
+
+// stretch returns the steps of p from the first to the last room or
+// tunnel it shares with q, the long tunnels leading in and out included.
+func stretch(p, q []*Room, steps []step) zone {
+	inner := q[1 : len(q)-1]
+	shares := func(st step) bool {
+		if st.room != nil {
+			return slices.Contains(inner, st.room)
 		}
-		if len(evts) > 0 {
-			sort.Slice(evts, func(i, j int) bool { return evts[i].id < evts[j].id })
-			line := make([]string, len(evts))
-			for i, e := range evts {
-				line[i] = fmt.Sprintf("L%d-%s", e.id, e.room.Name)
+		for i := 1; i < len(q); i++ {
+			if tunnelOf(q[i-1], q[i]) == tunnelOf(st.from, st.to) {
+				return true
 			}
-			moves = append(moves, strings.Join(line, " "))
 		}
+		return false
 	}
-	return moves
+	z := zone{lo: len(steps), hi: -1}
+	for k := 1; k < len(steps)-1; k++ {
+		if shares(steps[k]) {
+			z.lo, z.hi = min(z.lo, k), max(z.hi, k)
+		}
+	}
+	for z.lo > 1 && steps[z.lo-1].room == nil {
+		z.lo--
+	}
+	for z.hi < len(steps)-2 && steps[z.hi+1].room == nil {
+		z.hi++
+	}
+	return z
+}
+
+// canMove reports whether an ant may move from cur onto next this turn:
+// next must be open and have room for it and, when the ant leaves a room,
+// the tunnel must be open, not already used by as many ants as its width
+// this turn and free of ants coming the other way.
+func (s *Simulation) canMove(cur, next step) bool {
+	turn := s.turn + 1
+	if cur.room != nil {
+		to := next.room
+		if to == nil {
+			to = next.to
+		}
+		if s.entered[tunnelOf(cur.room, to)] >= cur.room.LinkWidth(to) || cur.room.linkClosedAt(to, turn) || s.crossing(to, cur.room) {
+			return false
+		}
+	}
+	if next.room != nil && closedAt(next.room.Closed, turn) {
+		return false
+	}
+	if s.g.isEnd(next.room) {
+		return true
+	}
+	if next.room != nil {
+		return s.occupancy[next] < next.room.capacity()
+	}
+	return s.occupancy[next] < next.from.LinkWidth(next.to)
+}
 
+// crossing reports whether some ant is inside the tunnel from a to b.
+func (s *Simulation) crossing(a, b *Room) bool {
+	for k := 1; k < a.LinkLength(b); k++ {
+		if s.occupancy[step{from: a, to: b, k: k}] > 0 {
+			return true
+		}
+	}
+	return false
+}
+
+// leave takes an ant off st.
+func (s *Simulation) leave(st step) {
+	if s.occupancy[st]--; s.occupancy[st] <= 0 {
+		delete(s.occupancy, st)
+	}
+}
+
+// enter moves ant id from cur onto st, recording a move when st is a room.
+func (s *Simulation) enter(evts []Move, id int, cur, st step) []Move {
+	if cur.room != nil {
+		to := st.room
+		if to == nil {
+			to = st.to
+		}
+		s.entered[tunnelOf(cur.room, to)]++
+	}
+	s.moves++
+	for z, zn := range s.zones {
+		if zn.path == s.first[s.route[id]] && zn.lo == s.pos[id] {
+			s.inZone[z]++
+		} else if zn.path == s.first[s.route[id]] && zn.hi+1 == s.pos[id] {
+			s.inZone[z]--
+		}
+	}
+	if s.g.isEnd(st.room) {
+		s.finished = append(s.finished, id+1)
+	} else {
+		s.occupancy[st]++
+	}
+	if st.room != nil {
+		evts = append(evts, Move{Ant: id + 1, Room: st.room})
+	}
+	return evts
+}
+
+// Clone returns an independent copy of the simulation, which can be
+// stepped without affecting s.
+func (s *Simulation) Clone() *Simulation {
+	c := *s
+	c.queues = slices.Clone(s.queues)
+	c.pos = slices.Clone(s.pos)
+	c.started = slices.Clone(s.started)
+	c.ready = slices.Clone(s.ready)
+	c.occupancy = maps.Clone(s.occupancy)
+	c.finished = slices.Clone(s.finished)
+	c.inZone = slices.Clone(s.inZone)
+	return &c
+}
+
+// Snapshot returns a copy of the current state.
+func (s *Simulation) Snapshot() Snapshot {
+	snap := Snapshot{
+		Turn:     s.turn,
+		Ants:     make([]*Room, len(s.route)),
+		InTunnel: make([]*Room, len(s.route)),
+		Rooms:    map[*Room][]int{},
+		Waiting:  make([][]int, len(s.queues)),
+		Finished: append([]int{}, s.finished...),
+		Moves:    append([]Move{}, s.last...),
+	}
+	for id := range s.route {
+		st := s.steps[s.route[id]][s.pos[id]]
+		snap.Ants[id] = st.room
+		if st.room == nil {
+			snap.Ants[id], snap.InTunnel[id] = st.from, st.to
+		} else if !s.g.isStart(st.room) && !s.g.isEnd(st.room) {
+			snap.Rooms[st.room] = append(snap.Rooms[st.room], id+1)
+		}
+	}
+	for i, q := range s.queues {
+		snap.Waiting[i] = make([]int, len(q))
+		for j, ant := range q {
+			snap.Waiting[i][j] = ant + 1
+		}
+	}
+	return snap
+}
+
+// Snapshots runs a whole simulation and returns the state after every
+// turn, starting with the initial state at turn 0. It keeps every ant's
+// position for every turn, so it is meant for small colonies; step a
+// Simulation instead to follow a large one.
+func Snapshots(g *Graph, paths [][]*Room, obj Objective) []Snapshot {
+	if len(paths) == 0 {
+		return nil
+	}
+	sim := NewSimulation(g, paths, obj)
+	snaps := []Snapshot{sim.Snapshot()}
+	for !sim.Done() {
+		sim.Step()
+		snaps = append(snaps, sim.Snapshot())
+	}
+	return snaps
+}
+
+// simulate runs the ants, route[i] being the path of ant i+1, and returns
+// the moves of each turn, or nil if the ants get stuck. Turns in which
+// every moving ant stays inside a long tunnel have no moves.
+func simulate(g *Graph, paths [][]*Room, route []int) [][]Move {
+	sim := newSimulation(g, paths, route)
+	var turns [][]Move
+	for !sim.Done() {
+		turns = append(turns, sim.Step())
+		if sim.stuck() {
+			return nil
+		}
+	}
+	return turns
 }
@@ -0,0 +1,217 @@
+package utils
+
+import "slices"
+
+// Stats summarises the shape of a colony.
+type Stats struct {
+	Rooms         int
+	Links         int
+	Degrees       map[int]int // degree -> number of rooms
+	Components    int
+	Distance      int // shortest start-end path in tunnels, -1 if unreachable
+	DisjointPaths int
+	Diameter      int      // longest shortest path between two rooms
+	DeadRooms     []string // rooms that lie on no simple start-end path
+}
+
+// ComputeStats gathers the statistics of g.
+func ComputeStats(g *Graph) Stats {
+	rooms, idx := roomIndex(g)
+	s := Stats{Rooms: len(rooms), Degrees: map[int]int{}, Distance: -1}
+	// Degrees and components ignore the direction of one-way tunnels.
+	adj := map[*Room][]*Room{}
+	for _, r := range rooms {
+		for _, nb := range r.Links {
+			if !hasNeighbor(nb, r) || r.Name < nb.Name {
+				adj[r] = append(adj[r], nb)
+				adj[nb] = append(adj[nb], r)
+				s.Links++
+			}
+		}
+	}
+	for _, r := range rooms {
+		s.Degrees[len(adj[r])]++
+	}
+
+	comp := make([]int, len(rooms))
+	for i := range comp {
+		comp[i] = -1
+	}
+	// The tunnels out of each room by index, and buffers for farthest,
+	// reused from room to room.
+	next := make([][]int, len(rooms))
+	for i, r := range rooms {
+		for _, nb := range r.Links {
+			next[i] = append(next[i], idx[nb])
+		}
+	}
+	dist, queue := make([]int, len(rooms)), make([]int, 0, len(rooms))
+	for i, r := range rooms {
+		if comp[i] == -1 {
+			comp[i] = s.Components
+			queue := []*Room{r}
+			for len(queue) > 0 {
+				cur := queue[0]
+				queue = queue[1:]
+				for _, nb := range adj[cur] {
+					if comp[idx[nb]] == -1 {
+						comp[idx[nb]] = s.Components
+						queue = append(queue, nb)
+					}
+				}
+			}
+			s.Components++
+		}
+		s.Diameter = max(s.Diameter, farthest(next, i, dist, queue))
+	}
+
+	if g.Start == nil || g.End == nil {
+		return s
+	}
+	if d, ok := distances(g.Start)[g.End]; ok {
+		s.Distance = d
+	}
+	s.DisjointPaths = MaxDisjointPaths(g)
+	alive := onSomePath(g, rooms, idx)
+	for i, r := range rooms {
+		if r != g.Start && r != g.End && !alive[i] {
+			s.DeadRooms = append(s.DeadRooms, r.Name)
+		}
+	}
+	return s
+}
+
+// distances runs a breadth-first search from r and returns the tunnel
+// distance to every reachable room.
+func distances(r *Room) map[*Room]int {
+	dist := map[*Room]int{r: 0}
+	queue := []*Room{r}
+	for len(queue) > 0 {
+		cur := queue[0]
+		queue = queue[1:]
+		for _, nb := range cur.Links {
+			if _, ok := dist[nb]; !ok {
+				dist[nb] = dist[cur] + 1
+				queue = append(queue, nb)
+			}
+		}
+	}
+	return dist
+}
+
+// farthest returns the tunnel distance from room from to the farthest
+// room it reaches, next listing the rooms each room leads to, searching
+// breadth first with the buffers dist and queue.
+func farthest(next [][]int, from int, dist, queue []int) int {
+	for i := range dist {
+		dist[i] = -1
+	}
+	dist[from] = 0
+	queue = append(queue[:0], from)
+	for k := 0; k < len(queue); k++ {
+		cur := queue[k]
+		for _, j := range next[cur] {
+			if dist[j] < 0 {
+				dist[j] = dist[cur] + 1
+				queue = append(queue, j)
+			}
+		}
+	}
+	return dist[queue[len(queue)-1]]
+}
+
+// onSomePath reports for each room whether it can be part of a simple
+// start-end path. That holds exactly when the room shares a biconnected
+// component with an extra tunnel joining start and end, as the room then
+// lies on a simple cycle through that tunnel. With one-way tunnels that
+// test no longer applies and deciding it is hard in general, so a room
+// only needs to be reachable from start and to reach end.
+func onSomePath(g *Graph, rooms []*Room, idx map[*Room]int) []bool {
+	alive := make([]bool, len(rooms))
+	for _, o := range rooms {
+		for _, nb := range o.Links {
+			if !hasNeighbor(nb, o) {
+				into := map[*Room][]*Room{}
+				for _, r := range rooms {
+					for _, nb := range r.Links {
+						into[nb] = append(into[nb], r)
+					}
+				}
+				fromStart := distances(g.Start)
+				toEnd := map[*Room]bool{g.End: true}
+				queue := []*Room{g.End}
+				for len(queue) > 0 {
+					cur := queue[0]
+					queue = queue[1:]
+					for _, r := range into[cur] {
+						if !toEnd[r] {
+							toEnd[r] = true
+							queue = append(queue, r)
+						}
+					}
+				}
+				for i, r := range rooms {
+					_, ok := fromStart[r]
+					alive[i] = ok && toEnd[r]
+				}
+				return alive
+			}
+		}
+	}
+
+	type edge struct{ to, id int }
+	adj := make([][]edge, len(rooms))
+	n := 0
+	for i, r := range rooms {
+		for _, nb := range r.Links {
+			if j := idx[nb]; i < j {
+				adj[i] = append(adj[i], edge{j, n})
+				adj[j] = append(adj[j], edge{i, n})
+				n++
+			}
+		}
+	}
+	s, e := idx[g.Start], idx[g.End]
+	adj[s] = append(adj[s], edge{e, n})
+	adj[e] = append(adj[e], edge{s, n})
+
+	// Tarjan's search, popping the edges of each biconnected component
+	// off stack as it is completed.
+	disc, low := make([]int, len(rooms)), make([]int, len(rooms))
+	var stack [][3]int // from, to, id
+	t := 0
+	var dfs func(u, parent int)
+	dfs = func(u, parent int) {
+		t++
+		disc[u], low[u] = t, t
+		for _, ed := range adj[u] {
+			v := ed.to
+			if ed.id == parent {
+				continue
+			}
+			if disc[v] == 0 {
+				stack = append(stack, [3]int{u, v, ed.id})
+				dfs(v, ed.id)
+				low[u] = min(low[u], low[v])
+				if low[v] >= disc[u] {
+					k := len(stack) - 1
+					for stack[k][2] != ed.id {
+						k--
+					}
+					comp := stack[k:]
+					stack = stack[:k]
+					if slices.ContainsFunc(comp, func(x [3]int) bool { return x[2] == n }) {
+						for _, x := range comp {
+							alive[x[0]], alive[x[1]] = true, true
+						}
+					}
+				}
+			} else if disc[v] < disc[u] {
+				stack = append(stack, [3]int{u, v, ed.id})
+				low[u] = min(low[u], disc[v])
+			}
+		}
+	}
+	dfs(s, -1)
+	return alive
+}
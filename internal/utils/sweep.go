@@ -0,0 +1,134 @@
+package utils
+
+// SweepPoint is the best solution found for one ant count.
+type SweepPoint struct {
+	Ants  int
+	Turns int
+	Paths [][]*Room
+}
+
+// Sweep solves g for every ant count from `from` to `to` inclusive,
+// ignoring g.Ants. Ties go to the solution with fewer paths. The second
+// result lists the breakpoints: for each number of paths above one, the
+// first ant count at which that many paths are strictly faster than any
+// fewer. Both are nil when start and end are not connected. The turns
+// are worked out from the path lengths, so colonies where ants also wait
+// for each other or for closures are rejected.
+func Sweep(g *Graph, from, to int) ([]SweepPoint, []SweepPoint, error) {
+	if err := lengthsOnly(g); err != nil {
+		return nil, nil, err
+	}
+	sets := pathSetsBySize(sortedPaths(g))
+	if len(sets) == 0 || from < 1 || to < from {
+		return nil, nil, nil
+	}
+	lengths := make([][]int, len(sets))
+	turns := make([]int, len(sets))
+	for k, set := range sets {
+		lengths[k] = pathLengths(set)
+	}
+	res := make([]SweepPoint, 0, to-from+1)
+	var breaks []SweepPoint
+	paidOff := make([]bool, len(sets))
+	for ants := from; ants <= to; ants++ {
+		best := -1
+		for k := range sets {
+			// Turn counts only grow with the number of ants, so each
+			// candidate resumes from where it stopped.
+			for delivered(turns[k], lengths[k]) < ants {
+				turns[k]++
+			}
+			if best == -1 || turns[k] < turns[best] {
+				if best != -1 && !paidOff[k] {
+					paidOff[k] = true
+					breaks = append(breaks, SweepPoint{Ants: ants, Turns: turns[k], Paths: sets[k]})
+				}
+				best = k
+			}
+		}
+		res = append(res, SweepPoint{Ants: ants, Turns: turns[best], Paths: sets[best]})
+	}
+	return res, breaks, nil
+}
+
+// lengthsOnly returns an error when the turns g takes do not follow from
+// the lengths of its paths: with several start rooms, checkpoints where
+// ants queue, closures or slow ants.
+func lengthsOnly(g *Graph) error {
+	why := ""
+	switch {
+	case len(g.Starts) > 0 || len(g.Colonies) > 0:
+		why = "several start rooms"
+	case len(g.Checkpoints) > 0:
+		why = "checkpoints"
+	case g.hasClosures():
+		why = "closures"
+	case len(g.Speeds) > 0:
+		why = "slow ants"
+	}
+	if why == "" {
+		return nil
+	}
+	return LemError{"ERROR: unsupported colony", "turns cannot be told from path lengths with " + why}
+}
+
+// pathSetsBySize returns, for every achievable number of disjoint paths
+// k, the k-path set with the smallest total length; entry k-1 holds it.
+//
+// These sets are the only candidates worth considering: a set with a
+// smaller total delivers at least as many ants in any number of turns, and
+// a set whose longest paths are idle is matched by a smaller set.
+func pathSetsBySize(all [][]*Room) [][][]*Room {
+	var best [][][]*Room
+	var bestLen []int
+	var rec func(int, [][]*Room, int, *pathUse)
+	rec = func(i int, cur [][]*Room, total int, used *pathUse) {
+		if k := len(cur); k > 0 {
+			if k > len(best) {
+				best = append(best, nil)
+				bestLen = append(bestLen, 0)
+			}
+			if best[k-1] == nil || total < bestLen[k-1] {
+				best[k-1] = append([][]*Room{}, cur...)
+				bestLen[k-1] = total
+			}
+		}
+		for j := i; j < len(all); j++ {
+			p := all[j]
+			if !used.fits(p) {
+				continue
+			}
+			used.add(p, 1)
+			rec(j, append(cur, p), total+PathLength(p), used)
+			used.add(p, -1)
+		}
+	}
+	rec(0, nil, 0, newPathUse())
+	return best
+}
+
+// MaxAntsForTurns returns how many ants g can move from start to end
+// within the given number of turns, ignoring g.Ants, together with the
+// paths that achieve it. It returns 0 and nil when no ant can arrive in
+// time, and an error for the colonies Sweep rejects.
+func MaxAntsForTurns(g *Graph, turns int) (int, [][]*Room, error) {
+	if err := lengthsOnly(g); err != nil {
+		return 0, nil, err
+	}
+	n, paths := maxAntsBound(g, turns)
+	return n, paths, nil
+}
+
+// maxAntsBound is MaxAntsForTurns for any colony, where it is an upper
+// bound: ants waiting for each other or for closures only deliver fewer.
+func maxAntsBound(g *Graph, turns int) (int, [][]*Room) {
+	best := 0
+	var paths [][]*Room
+	// Ties keep the smaller set, so no path in the result is idle.
+	for _, set := range pathSetsBySize(sortedPaths(g)) {
+		if n := delivered(turns, pathLengths(set)); n > best {
+			best, paths = n, set
+		}
+	}
+	return best, paths
+}
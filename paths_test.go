@@ -1,18 +1,36 @@
-
-
 package utils_test
 
 import (
 	"fmt"
 	"os"
 	"path/filepath"
+	"slices"
 	"strings"
 	"testing"
 
 	"lem-in/utils"
-
 )
 
+// writeMap stores data in a temporary file and returns its path.
+func writeMap(t *testing.T, data string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "in.txt")
+	if err := os.WriteFile(path, []byte(data), 0644); err != nil {
+		t.Fatalf("write temp file: %v", err)
+	}
+	return path
+}
+
+// parseMap parses the colony in data with the extensions of opts.
+func parseMap(t *testing.T, data string, opts utils.ParseOptions) *utils.Graph {
+	t.Helper()
+	g, _, err := utils.ParseInputWith(writeMap(t, data), opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return g
+}
+
 func TestExamplesTurns(t *testing.T) {
 	cases := []struct {
 		file string
@@ -65,29 +83,392 @@ func TestParseInputErrors(t *testing.T) {
 		},
 	}
 	for _, c := range cases {
-		dir := t.TempDir()
-		path := filepath.Join(dir, "in.txt")
-		if err := os.WriteFile(path, []byte(c.data), 0644); err != nil {
-			t.Fatalf("write temp file: %v", err)
-		}
-		_, _, err := utils.ParseInput(path)
+		_, _, err := utils.ParseInput(writeMap(t, c.data))
 		if err == nil || !strings.Contains(err.Error(), c.msg) {
 			t.Errorf("%s: expected to contain %q, got %v", c.name, c.msg, err)
 		}
 	}
 }
 
+func TestDirectiveLikeComments(t *testing.T) {
+	data := "4\n##speed up\n##start\n0 0 3\n##capacity planning\n2 2 5\n##closed for maintenance\n3 4 0\n##end\n1 8 3\n" +
+		"##length unknown\n0-2\n##deadline tomorrow\n2-3\n##closed 0\n3-1\n"
+	g, _, err := utils.ParseInput(writeMap(t, data))
+	if err != nil {
+		t.Fatalf("comments rejected: %v", err)
+	}
+	if moves := utils.SimulateMulti(g, utils.FindPaths(g)); len(moves) != 6 {
+		t.Errorf("got %d turns, want 6 as without the comments", len(moves))
+	}
+}
+
 func TestDuplicateLinkIsRejected(t *testing.T) {
 	data := "2\n##start\nA 0 0\n##end\nB 1 0\nA-B\nB-A\n"
-	dir := t.TempDir()
-	path := filepath.Join(dir, "in.txt")
-	if err := os.WriteFile(path, []byte(data), 0644); err != nil {
-		t.Fatalf("write temp file: %v", err)
-	}
-	_, _, err := utils.ParseInput(path)
+	_, _, err := utils.ParseInput(writeMap(t, data))
 
 	if err == nil || !strings.Contains(err.Error(), "ERROR: invalid data format") || !strings.Contains(err.Error(), "Reason: duplicate link") {
 		t.Fatalf("expected duplicate link error, got %v", err)
 	}
 }
 
+func TestObjectiveArrival(t *testing.T) {
+	g, _, err := utils.ParseInput("examples/example01.txt")
+	if err != nil {
+		t.Fatal(err)
+	}
+	base := utils.Evaluate(g.Ants, utils.FindPaths(g), utils.ObjectiveNone)
+	paths := utils.FindPathsWith(g, utils.ObjectiveArrival)
+	m := utils.Evaluate(g.Ants, paths, utils.ObjectiveArrival)
+	if m.Turns != base.Turns || m.AvgArrival >= base.AvgArrival {
+		t.Errorf("arrival objective gives %+v, default %+v", m, base)
+	}
+	if moves := utils.SimulateWith(g, paths, utils.ObjectiveArrival); len(moves) != m.Turns {
+		t.Errorf("simulation took %d turns, want %d", len(moves), m.Turns)
+	}
+}
+
+func TestWeightedTunnels(t *testing.T) {
+	data := "3\n##start\ns 0 0\na 1 0\nb 1 1\n##end\ne 2 0\ns-a 4\na-e\n##length 2\ns-b\nb-e\n"
+	g := parseMap(t, data, utils.ParseOptions{})
+	paths := utils.FindPaths(g)
+	if len(paths) != 1 || utils.PathLength(paths[0]) != 3 {
+		t.Fatalf("got %d paths, first of length %d", len(paths), utils.PathLength(paths[0]))
+	}
+	want := []string{"", "L1-b", "L1-e L2-b", "L2-e L3-b", "L3-e"}
+	moves := utils.SimulateMulti(g, paths)
+	if strings.Join(moves, "\n") != strings.Join(want, "\n") {
+		t.Errorf("got moves %q, want %q", moves, want)
+	}
+	// The tunnel of length 2 is a single move, in the metrics as in the
+	// summary.
+	m := utils.Evaluate(g.Ants, paths, utils.ObjectiveNone)
+	s := utils.Summarize(g, paths, utils.ObjectiveNone)
+	if m.Moves != 6 || s.Moves != 6 {
+		t.Errorf("got %d moves evaluated and %d summarized, want 6", m.Moves, s.Moves)
+	}
+}
+
+func TestRoomCapacity(t *testing.T) {
+	data := "6\n##start\ns 0 0\na 1 0\nb 1 1\n##capacity 2\nh 2 0\nc 3 0\nd 3 1\n##end\ne 4 0\n" +
+		"s-a\ns-b\na-h\nb-h\nh-c\nh-d\nc-e\nd-e\n"
+	g := parseMap(t, data, utils.ParseOptions{})
+	paths := utils.FindPaths(g)
+	if len(paths) != 2 || utils.MaxDisjointPaths(g) != 2 {
+		t.Fatalf("got %d paths through the shared room, want 2", len(paths))
+	}
+	moves := utils.SimulateMulti(g, paths)
+	if len(moves) != 6 {
+		t.Errorf("got %d turns, want 6", len(moves))
+	}
+	for _, s := range utils.Snapshots(g, paths, utils.ObjectiveNone) {
+		if n := len(s.Rooms[g.Rooms["h"]]); n > 2 {
+			t.Errorf("turn %d: %d ants in a room of capacity 2", s.Turn, n)
+		}
+	}
+}
+
+func TestTunnelWidth(t *testing.T) {
+	data := "6\n##start\ns 0 0\n##capacity 3\na 1 0\n##capacity 3\nb 2 0\n##end\ne 3 0\ns-a x3\na-b x3\nb-e 1 x3\n"
+	g := parseMap(t, data, utils.ParseOptions{})
+	paths := utils.FindPaths(g)
+	if len(paths) != 3 || utils.MaxDisjointPaths(g) != 3 {
+		t.Fatalf("got %d paths through the wide tunnels, want 3", len(paths))
+	}
+	moves := utils.SimulateMulti(g, paths)
+	if len(moves) != 4 {
+		t.Errorf("got %d turns, want 4", len(moves))
+	}
+	if err := utils.CheckMoves(g, moves); err != nil {
+		t.Errorf("simulated moves rejected: %v", err)
+	}
+	if err := utils.CheckMoves(g, []string{"L1-a L2-a L3-a L4-a", "L1-b L2-b L3-b L4-b", "L1-e L2-e L3-e L4-e L5-a L6-a", "L5-b L6-b", "L5-e L6-e"}); err == nil {
+		t.Error("four ants entering a tunnel of width 3 accepted")
+	}
+
+	// Copies that save no turn are left out, however wide the tunnel.
+	g = parseMap(t, "4\n##start\nA 0 0\n##end\nB 1 0\nA-B x3\n", utils.ParseOptions{})
+	if paths := utils.FindPaths(g); len(paths) != 2 {
+		t.Errorf("got %d copies of A-B for 4 ants, want 2", len(paths))
+	}
+	g = parseMap(t, "100000\n##start\nA 0 0\n##end\nB 1 0\nA-B x100000\n", utils.ParseOptions{})
+	if moves := utils.SimulateMulti(g, utils.FindPaths(g)); len(moves) != 1 {
+		t.Errorf("got %d turns for 100000 ants through a tunnel as wide, want 1", len(moves))
+	}
+}
+
+func TestOneWayTunnels(t *testing.T) {
+	// The short way through a is a drop that can only be taken upwards.
+	data := "2\n##start\ns 0 0\na 1 0\nb 1 1\nc 2 1\n##end\ne 3 0\ne->a\ns-a\ns-b\n##oneway\nb-c\nc-e\n"
+	g := parseMap(t, data, utils.ParseOptions{})
+	paths := utils.FindPaths(g)
+	if len(paths) != 1 || len(paths[0]) != 4 {
+		t.Fatalf("got paths %v, want s b c e", paths)
+	}
+	if n := utils.MaxDisjointPaths(g); n != 1 {
+		t.Errorf("got %d disjoint paths, want 1", n)
+	}
+	if err := utils.CheckMoves(g, []string{"L1-a L2-b", "L1-e L2-c", "L2-e"}); err == nil {
+		t.Error("move against a one-way tunnel accepted")
+	}
+	if err := utils.CheckMoves(g, utils.SimulateMulti(g, paths)); err != nil {
+		t.Errorf("simulated moves rejected: %v", err)
+	}
+
+	// A ">" in a room name does not make a one-way tunnel.
+	g = parseMap(t, "1\n##start\ns 0 0\nup>down 1 0\n##end\ne 2 0\ns-up>down\nup>down->e\n", utils.ParseOptions{})
+	if paths := utils.FindPaths(g); len(paths) != 1 || paths[0][1].Name != "up>down" {
+		t.Fatalf("got paths %v through up>down", paths)
+	}
+	if nb := g.Neighbors("e"); len(nb) != 0 {
+		t.Errorf("one-way tunnel up>down->e leads back from e to %v", nb)
+	}
+	if nb := g.Neighbors("up>down"); len(nb) != 2 {
+		t.Errorf("got neighbours %v of up>down, want s and e", nb)
+	}
+}
+
+func TestMultipleTerminals(t *testing.T) {
+	data := "7\n##start 4\ns1 0 0\n##start\ns2 0 4\na 1 0\nb 1 2\nc 1 4\nh 2 2\n##end\ne1 3 0\n##end\ne2 3 4\n" +
+		"s1-a\na-e1\ns1-b\nb-h\ns2-c\nc-e2\ns2-h\nh-e1\n"
+	if _, _, err := utils.ParseInput(writeMap(t, data)); err == nil {
+		t.Fatal("several end rooms accepted without the extension")
+	}
+	g := parseMap(t, data, utils.ParseOptions{MultiTerminal: true})
+	if len(g.Starts) != 2 || len(g.Ends) != 2 || g.StartAnts[0] != 4 || g.StartAnts[1] != 3 {
+		t.Fatalf("got starts %v with ants %v and %d ends", g.Starts, g.StartAnts, len(g.Ends))
+	}
+	if n := utils.MaxDisjointPaths(g); n != 3 {
+		t.Errorf("got %d disjoint paths, want 3", n)
+	}
+	moves := utils.SimulateMulti(g, utils.FindPaths(g))
+	if len(moves) != 4 {
+		t.Errorf("got %d turns, want 4", len(moves))
+	}
+	if err := utils.CheckMoves(g, moves); err != nil {
+		t.Errorf("simulated moves rejected: %v", err)
+	}
+}
+
+func TestColonies(t *testing.T) {
+	// Colony a crosses colony b's way at m.
+	data := "5\n##start a 3\nw 0 0\n##end a\ne 4 0\n##start b\nn 2 2\n##end b\ns 2 -2\nm 2 0\np 1 1\nq 3 1\n" +
+		"w-m\nm-e\nn-m\nm-s\nw-p\np-n\nn-q\nq-e\n"
+	g := parseMap(t, data, utils.ParseOptions{Colonies: true})
+	if len(g.Colonies) != 2 || g.Colonies[0].Ants != 3 || g.Colonies[1].Ants != 2 {
+		t.Fatalf("got colonies %+v", g.Colonies)
+	}
+	paths := utils.FindPaths(g)
+	if len(paths) != 2 {
+		t.Fatalf("got %d paths, want one per colony", len(paths))
+	}
+	moves := utils.SimulateMulti(g, paths)
+	if len(moves) != 6 || moves[0] != "La1-m" {
+		t.Errorf("got moves %q", moves)
+	}
+	if err := utils.CheckMoves(g, moves); err != nil {
+		t.Errorf("simulated moves rejected: %v", err)
+	}
+	if err := utils.CheckMoves(g, []string{"La1-m", "La1-s La2-m", "La2-e La3-m", "La3-e Lb1-m", "Lb1-s Lb2-m", "Lb2-s"}); err == nil {
+		t.Error("ant of colony a accepted in colony b's end room")
+	}
+	if err := utils.CheckMoves(g, []string{"La1-p Lb1-m", "La1-n La2-p Lb1-s Lb2-m", "La1-q La2-n La3-p Lb2-s", "La1-e La2-q La3-n", "La2-e La3-q", "La3-e"}); err == nil {
+		t.Error("ants of colony a accepted through colony b's start room")
+	}
+}
+
+func TestCheckpoints(t *testing.T) {
+	// The direct way through x skips the checkpoint k.
+	data := "6\n##start\ns 0 0\na 1 0\nb 1 2\n##checkpoint\n##capacity 2\nk 2 1\nc 3 0\nd 3 2\nx 2 -2\n##end\ne 4 1\n" +
+		"s-a\ns-b\na-k\nb-k\nk-c\nk-d\nc-e\nd-e\ns-x\nx-e\n"
+	g := parseMap(t, data, utils.ParseOptions{})
+	paths := utils.FindPaths(g)
+	if len(paths) != 2 {
+		t.Fatalf("got %d paths, want 2 meeting at the checkpoint", len(paths))
+	}
+	for _, p := range paths {
+		if p[2] != g.Rooms["k"] {
+			t.Errorf("path %v does not pass the checkpoint", p)
+		}
+	}
+	moves := utils.SimulateMulti(g, paths)
+	if len(moves) != 6 {
+		t.Errorf("got %d turns, want 6", len(moves))
+	}
+	if err := utils.CheckMoves(g, moves); err != nil {
+		t.Errorf("simulated moves rejected: %v", err)
+	}
+	g.Rooms["k"].Capacity = 1
+	if moves := utils.SimulateMulti(g, utils.FindPaths(g)); len(moves) != 9 {
+		t.Errorf("got %d turns through a checkpoint holding one ant, want 9", len(moves))
+	}
+}
+
+func TestCheckpointOnlyPath(t *testing.T) {
+	// Every inner room of s-c-e is a checkpoint; the path is chosen once
+	// per ant the checkpoint holds.
+	for capacity, want := range map[int]int{1: 4, 2: 3} {
+		data := fmt.Sprintf("3\n##start\ns 0 0\n##checkpoint\n##capacity %d\nc 2 0\n##end\ne 3 0\ns-c x2\nc-e x2\n", capacity)
+		g := parseMap(t, data, utils.ParseOptions{})
+		paths := utils.FindPaths(g)
+		if len(paths) != capacity {
+			t.Errorf("capacity %d: got %d paths, want %d", capacity, len(paths), capacity)
+		}
+		moves := utils.SimulateMulti(g, paths)
+		if len(moves) != want {
+			t.Errorf("capacity %d: got %d turns, want %d", capacity, len(moves), want)
+		}
+		if err := utils.CheckMoves(g, moves); err != nil {
+			t.Errorf("capacity %d: simulated moves rejected: %v", capacity, err)
+		}
+	}
+}
+
+func TestClosures(t *testing.T) {
+	data := "4\n##start\ns 0 0\n##closed 2-4\na 1 0\nb 1 1\nc 2 1\n##end\ne 3 0\n" +
+		"s-a\na-e\ns-b\nb-c\n##closed\nc-e\n##closed 1\nb-e\n"
+	g := parseMap(t, data, utils.ParseOptions{})
+	paths := utils.FindPaths(g)
+	for _, p := range paths {
+		if p[len(p)-2] == g.Rooms["c"] {
+			t.Errorf("path %v uses a closed tunnel", p)
+		}
+	}
+	want := []string{"L1-a L2-b", "L1-e L2-e L3-b", "L3-e L4-b", "L4-e"}
+	moves := utils.SimulateMulti(g, paths)
+	if strings.Join(moves, "\n") != strings.Join(want, "\n") {
+		t.Errorf("got moves %q, want %q", moves, want)
+	}
+	if err := utils.CheckMoves(g, moves); err != nil {
+		t.Errorf("simulated moves rejected: %v", err)
+	}
+	if err := utils.CheckMoves(g, []string{"L1-a L2-b", "L1-e L3-a L2-e", "L3-e L4-b", "L4-e"}); err == nil {
+		t.Error("ant entering a closed room accepted")
+	}
+}
+
+func TestWaitInsideTunnel(t *testing.T) {
+	// b is closed until turn 5, so L1 waits inside the long tunnel a-b
+	// and L2 takes its place in a.
+	g := parseMap(t, "2\n##start\ns 0 0\na 1 0\n##closed 1-5\nb 2 0\n##end\ne 3 0\ns-a\na-b 3\nb-e\n", utils.ParseOptions{})
+	moves := utils.SimulateMulti(g, utils.FindPaths(g))
+	if len(moves) != 8 || moves[1] != "L2-a" || moves[5] != "L1-b" {
+		t.Errorf("got moves %q", moves)
+	}
+	// Left out, the idle turns no longer print as empty lines.
+	if got, want := utils.ArrivalTurns(moves), []string{"L1-a", "L2-a", "L1-b", "L1-e L2-b", "L2-e"}; !slices.Equal(got, want) {
+		t.Errorf("got arrival turns %q, want %q", got, want)
+	}
+	if err := utils.CheckMoves(g, moves); err != nil {
+		t.Errorf("simulated moves rejected: %v", err)
+	}
+	if err := utils.CheckMoves(g, []string{"L1-a", "L2-a", "", "L1-b", "", "L1-e L2-b", "L2-e"}); err == nil {
+		t.Error("ant entering b while it is closed accepted")
+	}
+}
+
+func TestAntSpeeds(t *testing.T) {
+	data := "3\n##speed 3 1\n##start\ns 0 0\na 1 0\nb 1 1\nc 2 1\n##end\ne 3 0\ns-a\na-e\ns-b\nb-c\nc-e\n"
+	g := parseMap(t, data, utils.ParseOptions{})
+	// The slow ant, numbered last, follows the fast ones on the short path
+	// rather than crawl alone along the long one.
+	want := []string{"L1-a", "L1-e L2-a", "L2-e L3-a", "", "", "L3-e"}
+	moves := utils.SimulateMulti(g, utils.FindPaths(g))
+	if strings.Join(moves, "\n") != strings.Join(want, "\n") {
+		t.Errorf("got moves %q, want %q", moves, want)
+	}
+	if err := utils.CheckMoves(g, moves); err != nil {
+		t.Errorf("simulated moves rejected: %v", err)
+	}
+	if err := utils.CheckMoves(g, []string{"L3-a L1-b", "L1-c L2-a", "L1-e L2-e", "L3-e"}); err == nil {
+		t.Error("slow ant moving every turn accepted")
+	}
+	if _, _, err := utils.ParseInput(writeMap(t, strings.Replace(data, "##speed 3 1", "##speed 3 4", 1))); err == nil {
+		t.Error("speed class larger than the ant count accepted")
+	}
+}
+
+func TestDeadlines(t *testing.T) {
+	data := "4\n##deadline 3 3\n##deadline 1 1\n##start\ns 0 0\na 1 0\nb 1 1\nc 2 1\n##end\ne 3 0\ns-a\na-e\ns-b\nb-c\nc-e\n"
+	g := parseMap(t, data, utils.ParseOptions{})
+	paths := utils.FindPaths(g)
+	want := []string{"L1-a L3-b", "L1-e L2-a L3-c", "L2-e L3-e L4-a", "L4-e"}
+	moves := utils.SimulateMulti(g, paths)
+	if strings.Join(moves, "\n") != strings.Join(want, "\n") {
+		t.Errorf("got moves %q, want %q", moves, want)
+	}
+	late := utils.LateAnts(g, paths, utils.ObjectiveNone)
+	// No ant can arrive by turn 1, so that deadline goes to the last ant
+	// and the three due by turn 3 make it.
+	wantLate := []utils.LateAnt{{Ant: "4", Deadline: 1, Arrival: 4, Infeasible: true}}
+	if !slices.Equal(late, wantLate) {
+		t.Errorf("got late ants %+v, want %+v", late, wantLate)
+	}
+
+	// A single path of length 2 brings one ant by turn 2, not five.
+	g = parseMap(t, "5\n##deadline 2 5\n##start\ns 0 0\na 1 0\n##end\ne 2 0\ns-a\na-e\n", utils.ParseOptions{})
+	late = utils.LateAnts(g, utils.FindPaths(g), utils.ObjectiveNone)
+	if len(late) != 4 {
+		t.Fatalf("got late ants %+v, want 4", late)
+	}
+	for _, l := range late {
+		if !l.Infeasible {
+			t.Errorf("L%s: got a plain miss, want an infeasible deadline", l.Ant)
+		}
+	}
+}
+
+func TestOpposingColonies(t *testing.T) {
+	// Colony a goes from w to e and colony b back; the long tunnel m-e
+	// cannot carry both ways at once.
+	data := "6\n##start a 3\n##end b\nw 0 0\n##start b 3\n##end a\ne 6 0\nn1 2 2\nn2 4 2\ns1 2 -2\ns2 4 -2\nm 3 0\n" +
+		"w-n1\nn1-n2\nn2-e\nw-s1\ns1-s2\ns2-e\nw-m\nm-e 3\n"
+	g := parseMap(t, data, utils.ParseOptions{Colonies: true})
+	moves := utils.SimulateMulti(g, utils.FindPaths(g))
+	if len(moves) != 5 {
+		t.Errorf("got %d turns, want 5", len(moves))
+	}
+	if err := utils.CheckMoves(g, moves); err != nil {
+		t.Errorf("simulated moves rejected: %v", err)
+	}
+	bad := map[string][]string{
+		"meet in a tunnel": {"La1-m", "", "Lb1-m", "La1-e"},
+		"swap":             {"La1-n1 Lb1-n2", "La1-n2 Lb1-n1"},
+	}
+	for name, m := range bad {
+		if err := utils.CheckMoves(g, m); err == nil {
+			t.Errorf("%s: invalid moves accepted", name)
+		}
+	}
+
+	// With a single corridor the groups take turns through it.
+	g = parseMap(t, "4\n##start a 2\n##end b\nA 0 0\n##start b\n##end a\nB 3 0\nx 1 0\ny 2 0\nA-x\nx-y\ny-B\n", utils.ParseOptions{Colonies: true})
+	corridor := utils.SimulateMulti(g, utils.FindPaths(g))
+	if len(corridor) != 8 {
+		t.Errorf("got corridor moves %q, want 8 turns", corridor)
+	}
+	if err := utils.CheckMoves(g, corridor); err != nil {
+		t.Errorf("simulated corridor moves rejected: %v", err)
+	}
+}
+
+func TestEuclidCost(t *testing.T) {
+	data := "1\n##start\ns 0 0\na 3 4\nb 1 1\n##end\ne 6 8\ns-a\na-e\ns-b\n##length 2\nb-e\n"
+	g := parseMap(t, data, utils.ParseOptions{Euclid: true})
+	// s-b is sqrt(2) apart, rounded up to 2, and ##length overrides the
+	// distance of b-e.
+	for _, l := range []struct {
+		a, b string
+		want int
+	}{{"s", "a", 5}, {"a", "e", 5}, {"s", "b", 2}, {"b", "e", 2}} {
+		if got := g.Rooms[l.a].LinkLength(g.Rooms[l.b]); got != l.want {
+			t.Errorf("%s-%s: got length %d, want %d", l.a, l.b, got, l.want)
+		}
+	}
+	moves := utils.SimulateMulti(g, utils.FindPaths(g))
+	if len(moves) != 4 {
+		t.Errorf("got %d turns, want 4", len(moves))
+	}
+	if err := utils.CheckMoves(g, moves); err != nil {
+		t.Errorf("simulated moves rejected: %v", err)
+	}
+}
@@ -0,0 +1,139 @@
+package utils_test
+
+import (
+	"slices"
+	"strings"
+	"testing"
+
+	"lem-in/utils"
+)
+
+func TestSnapshots(t *testing.T) {
+	g, _, err := utils.ParseInput("examples/example01.txt")
+	if err != nil {
+		t.Fatal(err)
+	}
+	paths := utils.FindPaths(g)
+	snaps := utils.Snapshots(g, paths, utils.ObjectiveNone)
+	if want := len(utils.SimulateMulti(g, paths)); len(snaps) != want+1 {
+		t.Fatalf("got %d snapshots, want %d", len(snaps), want+1)
+	}
+	for _, s := range snaps {
+		for i, r := range s.Ants {
+			if r == g.Start || r == g.End {
+				continue
+			}
+			if ants := s.Rooms[r]; len(ants) != 1 || ants[0] != i+1 {
+				t.Fatalf("turn %d: room %s holds %v, ant %d is there", s.Turn, r.Name, ants, i+1)
+			}
+		}
+	}
+	first, last := snaps[0], snaps[len(snaps)-1]
+	waiting := 0
+	for _, q := range first.Waiting {
+		waiting += len(q)
+	}
+	if waiting != g.Ants || len(first.Finished) != 0 {
+		t.Errorf("turn 0: %d waiting, %d finished", waiting, len(first.Finished))
+	}
+	if len(last.Finished) != g.Ants {
+		t.Errorf("last turn: %d of %d ants finished", len(last.Finished), g.Ants)
+	}
+}
+
+func TestSimulationClone(t *testing.T) {
+	g, _, err := utils.ParseInput("examples/example01.txt")
+	if err != nil {
+		t.Fatal(err)
+	}
+	paths := utils.FindPaths(g)
+	snaps := utils.Snapshots(g, paths, utils.ObjectiveNone)
+	sim := utils.NewSimulation(g, paths, utils.ObjectiveNone)
+	sim.Step()
+	sim.Step()
+	c := sim.Clone()
+	for !sim.Done() {
+		sim.Step()
+	}
+	for turn := 2; turn < len(snaps); turn++ {
+		s := c.Snapshot()
+		if want := snaps[turn]; !slices.Equal(s.Ants, want.Ants) || !slices.Equal(s.Finished, want.Finished) {
+			t.Fatalf("turn %d: clone has ants at %v finished %v, want %v finished %v", turn, s.Ants, s.Finished, want.Ants, want.Finished)
+		}
+		c.Step()
+	}
+	if !c.Done() {
+		t.Error("clone did not finish with the original")
+	}
+}
+
+func TestCheckMoves(t *testing.T) {
+	g, _, err := utils.ParseInput("examples/example00.txt")
+	if err != nil {
+		t.Fatal(err)
+	}
+	moves := utils.SimulateMulti(g, utils.FindPaths(g))
+	if err := utils.CheckMoves(g, moves); err != nil {
+		t.Fatalf("simulated moves rejected: %v", err)
+	}
+	bad := map[string][]string{
+		"unfinished":   moves[:len(moves)-1],
+		"teleport":     append([]string{"L1-1"}, moves[1:]...),
+		"moves twice":  append([]string{moves[0] + " " + moves[0]}, moves[1:]...),
+		"unknown ant":  append([]string{"L9-2"}, moves...),
+		"invalid move": append([]string{"9-2"}, moves...),
+		"leaves end":   slices.Concat(moves, []string{"L1-3", "L1-1"}),
+	}
+	for name, m := range bad {
+		if err := utils.CheckMoves(g, m); err == nil {
+			t.Errorf("%s: invalid moves accepted", name)
+		}
+	}
+}
+
+func TestScenario(t *testing.T) {
+	g := parseMap(t, "4\n##start\ns 0 0\na 1 0\nb 1 1\nc 2 1\n##end\ne 3 0\ns-a\na-e\ns-b\nb-c\nc-e\na-c\n", utils.ParseOptions{})
+	events, err := utils.ParseScenario(writeMap(t, "# a-e gives way\n2 collapse a-e\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	res, err := utils.RunScenario(g, events, utils.ObjectiveNone)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []string{"L1-a L2-b", "L1-c L3-a", "L1-e L2-c", "L2-e L3-c L4-a", "L3-e L4-c", "L4-e"}
+	if strings.Join(res.Moves, "\n") != strings.Join(want, "\n") {
+		t.Errorf("got moves %q, want %q", res.Moves, want)
+	}
+	if res.BaselineTurns != 4 || res.ExtraTurns() != 2 || res.Rerouted != 1 {
+		t.Errorf("got %d turns instead of %d with %d rerouted, want 6 instead of 4 with 1", res.Turns, res.BaselineTurns, res.Rerouted)
+	}
+	if !slices.Contains(g.Rooms["a"].Links, g.Rooms["e"]) {
+		t.Error("scenario modified the colony")
+	}
+
+	if events, err = utils.ParseScenario(writeMap(t, "2 collapse s-a\n2 collapse s-b\n")); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := utils.RunScenario(g, events, utils.ObjectiveNone); err == nil {
+		t.Error("ants cut off from end reported no error")
+	}
+
+	// L1 is in a when a-k collapses and must still pass checkpoint k.
+	g = parseMap(t, "2\n##start\ns 0 0\na 1 0\nb 1 1\n##checkpoint\nk 2 0\n##end\ne 3 0\ns-a\na-k\nk-e\na-e\na-b\nb-k\n", utils.ParseOptions{})
+	if events, err = utils.ParseScenario(writeMap(t, "2 collapse a-k\n")); err != nil {
+		t.Fatal(err)
+	}
+	if res, err = utils.RunScenario(g, events, utils.ObjectiveNone); err != nil {
+		t.Fatal(err)
+	}
+	if err := utils.CheckMoves(g, res.Moves); err != nil {
+		t.Errorf("rerouted moves %q: %v", res.Moves, err)
+	}
+	if events, err = utils.ParseScenario(writeMap(t, "2 collapse a-k\n2 collapse b-k\n")); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := utils.RunScenario(g, events, utils.ObjectiveNone); err == nil {
+		t.Error("ants cut off from checkpoint reported no error")
+	}
+}
@@ -0,0 +1,134 @@
+package utils_test
+
+import (
+	"strconv"
+	"testing"
+
+	"lem-in/utils"
+)
+
+func TestComputeStats(t *testing.T) {
+	g, _, err := utils.ParseInput("examples/example01.txt")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := g.AddRoom("spur", 20, 20); err != nil {
+		t.Fatal(err)
+	}
+	if err := g.AddLink("h", "spur"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := g.AddRoom("island", 21, 21); err != nil {
+		t.Fatal(err)
+	}
+	s := utils.ComputeStats(g)
+	if s.Rooms != 16 || s.Links != 18 || s.Components != 2 {
+		t.Errorf("got %d rooms, %d links, %d components", s.Rooms, s.Links, s.Components)
+	}
+	if s.Distance != 4 || s.DisjointPaths != 3 {
+		t.Errorf("got distance %d, %d disjoint paths", s.Distance, s.DisjointPaths)
+	}
+	if len(s.DeadRooms) != 2 || s.DeadRooms[0] != "island" || s.DeadRooms[1] != "spur" {
+		t.Errorf("got dead rooms %v", s.DeadRooms)
+	}
+}
+
+func TestDeadRoomsLongChain(t *testing.T) {
+	// A 3000 room chain with a spur off every tenth room.
+	g := utils.NewGraph(1)
+	for i := 0; i < 3000; i++ {
+		if _, err := g.AddRoom("r"+strconv.Itoa(i), i, 0); err != nil {
+			t.Fatal(err)
+		}
+		if i > 0 {
+			if err := g.AddLink("r"+strconv.Itoa(i-1), "r"+strconv.Itoa(i)); err != nil {
+				t.Fatal(err)
+			}
+		}
+		if i%10 == 5 {
+			if _, err := g.AddRoom("x"+strconv.Itoa(i), i, 1); err != nil {
+				t.Fatal(err)
+			}
+			if err := g.AddLink("r"+strconv.Itoa(i), "x"+strconv.Itoa(i)); err != nil {
+				t.Fatal(err)
+			}
+		}
+	}
+	if err := g.SetStart("r0"); err != nil {
+		t.Fatal(err)
+	}
+	if err := g.SetEnd("r2999"); err != nil {
+		t.Fatal(err)
+	}
+	dead := utils.ComputeStats(g).DeadRooms
+	if len(dead) != 300 || dead[0][0] != 'x' {
+		t.Errorf("got %d dead rooms starting with %v, want the 300 spurs", len(dead), dead[:min(len(dead), 3)])
+	}
+}
+
+func TestBottlenecks(t *testing.T) {
+	g, _, err := utils.ParseInput("examples/example03.txt")
+	if err != nil {
+		t.Fatal(err)
+	}
+	got := utils.Bottlenecks(g)
+	if len(got) != 1 || got[0].Room != "4" || got[0].Turns != 6 || got[0].BypassTurns != 4 {
+		t.Errorf("got %+v", got)
+	}
+}
+
+func TestLinkSensitivity(t *testing.T) {
+	g, _, err := utils.ParseInput("examples/example00.txt")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, l := range utils.LinkSensitivity(g) {
+		if !l.Disconnects {
+			t.Errorf("%s-%s: expected removal to disconnect the colony", l.From, l.To)
+		}
+	}
+	g, _, err = utils.ParseInput("examples/example01.txt")
+	if err != nil {
+		t.Fatal(err)
+	}
+	report := utils.LinkSensitivity(g)
+	if len(report) != 17 || report[0].ExtraTurns != 1 || report[16].ExtraTurns != 0 {
+		t.Errorf("got %+v", report)
+	}
+}
+
+func TestLinkSensitivityLongTunnel(t *testing.T) {
+	// Every job must see s-a still 5 turns long once b-c is put back.
+	data := "10\n##start\ns 0 0\na 1 0\nb 1 1\nc 2 1\n##end\ne 3 0\ns-a 5\na-e\ns-b\nb-c\nc-e\n"
+	g := parseMap(t, data, utils.ParseOptions{})
+	base := utils.PathTurns(g.Ants, utils.FindPaths(g))
+	report := utils.LinkSensitivity(g)
+	if len(report) != 5 {
+		t.Fatalf("got %d tunnels, want 5", len(report))
+	}
+	for _, l := range report {
+		// Compare with a fresh copy, every worker reusing its own.
+		work := g.Clone()
+		work.RemoveLink(l.From, l.To)
+		want := utils.PathTurns(work.Ants, utils.FindPaths(work)) - base
+		if l.Disconnects || l.ExtraTurns != want {
+			t.Errorf("%s-%s: got %+v, want +%d", l.From, l.To, l, want)
+		}
+		if l.From+"-"+l.To == "b-c" && l.ExtraTurns != 6 {
+			t.Errorf("b-c: got +%d, want +6", l.ExtraTurns)
+		}
+	}
+}
+
+func TestBottlenecksEuclid(t *testing.T) {
+	// The twin of r lands right of z, far from s and e, yet must keep
+	// r's one-turn tunnels.
+	data := "10\n##start\ns 0 0\nr 1 0\n##end\ne 2 0\nz 10 10\ns-r\nr-e\n"
+	for _, euclid := range []bool{false, true} {
+		g := parseMap(t, data, utils.ParseOptions{Euclid: euclid})
+		got := utils.Bottlenecks(g)
+		if len(got) != 1 || got[0].Room != "r" || got[0].Turns != 11 || got[0].BypassTurns != 6 {
+			t.Errorf("euclid %v: got %+v", euclid, got)
+		}
+	}
+}
@@ -0,0 +1,28 @@
+package utils_test
+
+import (
+	"testing"
+
+	"lem-in/utils"
+)
+
+func TestSuggestLinks(t *testing.T) {
+	g, _, err := utils.ParseInput("examples/example00.txt")
+	if err != nil {
+		t.Fatal(err)
+	}
+	// A direct tunnel lets three ants go straight to end while the
+	// fourth takes the old way.
+	got := utils.SuggestLinks(g, 1, 0)
+	if len(got) != 1 || got[0].From+"-"+got[0].To != "0-1" || got[0].Turns != 3 {
+		t.Errorf("got %+v, want 0-1 in 3 turns", got)
+	}
+	// Start and end are 8 apart; within 5 only the shortcut to 3 is left.
+	got = utils.SuggestLinks(g, 1, 5)
+	if len(got) != 1 || got[0].From+"-"+got[0].To != "0-3" || got[0].Turns != 5 {
+		t.Errorf("within 5: got %+v, want 0-3 in 5 turns", got)
+	}
+	if len(g.Rooms["0"].Links) != 1 {
+		t.Error("suggestions modified the colony")
+	}
+}
@@ -0,0 +1,116 @@
+package utils_test
+
+import (
+	"slices"
+	"strconv"
+	"strings"
+	"testing"
+
+	"lem-in/utils"
+)
+
+func TestSummarize(t *testing.T) {
+	for _, name := range []string{"example00", "example01"} {
+		g, _, err := utils.ParseInput("examples/" + name + ".txt")
+		if err != nil {
+			t.Fatal(err)
+		}
+		paths := utils.FindPaths(g)
+		s := utils.Summarize(g, paths, utils.ObjectiveNone)
+		lines := utils.SimulateMulti(g, paths)
+
+		// Replay the moves, counting for every room the turns it ends
+		// with an ant inside.
+		at := map[string]string{}
+		arrivals := make([]int, g.Ants)
+		first := map[string]string{}
+		busy := map[string]int{}
+		moves := 0
+		for i, line := range lines {
+			for _, m := range strings.Fields(line) {
+				ant, room, _ := strings.Cut(m[1:], "-")
+				if _, ok := at[ant]; !ok {
+					first[ant] = room
+				}
+				at[ant] = room
+				if room == g.End.Name {
+					id, _ := strconv.Atoi(ant)
+					arrivals[id-1] = i + 1
+				}
+				moves++
+			}
+			held := map[string]bool{}
+			for _, room := range at {
+				if room != g.End.Name && !held[room] {
+					held[room] = true
+					busy[room]++
+				}
+			}
+		}
+		if s.Turns != len(lines) || s.Moves != moves {
+			t.Errorf("%s: got %d turns and %d moves, want %d and %d", name, s.Turns, s.Moves, len(lines), moves)
+		}
+		if !slices.Equal(s.Arrivals, arrivals) {
+			t.Errorf("%s: got arrivals %v, want %v", name, s.Arrivals, arrivals)
+		}
+		for i, p := range s.Paths {
+			n := 0
+			for _, room := range first {
+				if room == p.Rooms[1] {
+					n++
+				}
+			}
+			if p.Ants != n || p.Length != len(paths[i])-1 {
+				t.Errorf("%s: path %v has %d ants and length %d, want %d and %d", name, p.Rooms, p.Ants, p.Length, n, len(paths[i])-1)
+			}
+		}
+		if len(s.Utilization) == 0 {
+			t.Errorf("%s: no room utilization", name)
+		}
+		for _, u := range s.Utilization {
+			if u.Turns != busy[u.Room] {
+				t.Errorf("%s: room %s busy %d turns, want %d", name, u.Room, u.Turns, busy[u.Room])
+			}
+		}
+	}
+}
+
+func TestItineraries(t *testing.T) {
+	// example00 has a single path: L1 leaves start on turn 1 and L4
+	// waits there until turn 4.
+	g, _, err := utils.ParseInput("examples/example00.txt")
+	if err != nil {
+		t.Fatal(err)
+	}
+	paths := utils.FindPaths(g)
+	visits := utils.Itineraries(g, paths, utils.ObjectiveNone)
+	want := make([][]string, g.Ants)
+	for i := range want {
+		want[i] = []string{g.Start.Name + "@0"}
+	}
+	for i, line := range utils.SimulateMulti(g, paths) {
+		for _, m := range strings.Fields(line) {
+			ant, room, _ := strings.Cut(m[1:], "-")
+			id, _ := strconv.Atoi(ant)
+			want[id-1] = append(want[id-1], room+"@"+strconv.Itoa(i+1))
+		}
+	}
+	if len(visits) != g.Ants {
+		t.Fatalf("got %d itineraries, want %d", len(visits), g.Ants)
+	}
+	for i, v := range visits {
+		got := make([]string, len(v))
+		for j, vis := range v {
+			got[j] = vis.Room.Name + "@" + strconv.Itoa(vis.Turn)
+		}
+		if !slices.Equal(got, want[i]) {
+			t.Errorf("L%d: got %v, want %v", i+1, got, want[i])
+		}
+	}
+	if got := visits[0]; got[1].Turn != 1 {
+		t.Errorf("L1 left start on turn %d, want 1", got[1].Turn)
+	}
+	if got := visits[3]; got[1].Turn != 4 {
+		t.Errorf("L4 left start on turn %d, want 4 after waiting", got[1].Turn)
+	}
+}
@@ -0,0 +1,94 @@
+package utils_test
+
+import (
+	"testing"
+
+	"lem-in/utils"
+)
+
+func TestSweepMatchesFindPaths(t *testing.T) {
+	for _, file := range []string{
+		"examples/example00.txt",
+		"examples/example01.txt",
+		"examples/example03.txt",
+		"examples/example05.txt",
+	} {
+		g, _, err := utils.ParseInput(file)
+		if err != nil {
+			t.Fatalf("parse %s: %v", file, err)
+		}
+		points, _, err := utils.Sweep(g, 1, 50)
+		if err != nil {
+			t.Fatalf("sweep %s: %v", file, err)
+		}
+		for _, p := range points {
+			g.Ants = p.Ants
+			want := utils.PathTurns(p.Ants, utils.FindPaths(g))
+			if p.Turns != want {
+				t.Errorf("%s with %d ants: sweep gives %d turns, FindPaths %d", file, p.Ants, p.Turns, want)
+			}
+		}
+	}
+}
+
+func TestMaxAntsForTurns(t *testing.T) {
+	g, _, err := utils.ParseInput("examples/example01.txt")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for turns := 4; turns <= 20; turns++ {
+		ants, paths, err := utils.MaxAntsForTurns(g, turns)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := utils.PathTurns(ants, paths); got > turns {
+			t.Errorf("%d turns: %d ants on the returned paths need %d turns", turns, ants, got)
+		}
+		g.Ants = ants + 1
+		if got := utils.PathTurns(g.Ants, utils.FindPaths(g)); got <= turns {
+			t.Errorf("%d turns: %d ants also fit", turns, g.Ants)
+		}
+	}
+}
+
+func TestSweepBreakpoints(t *testing.T) {
+	// One path of length 2 takes n+1 turns for n ants; adding the path of
+	// length 4 takes (n+4)/2 rounded up, strictly fewer from 4 ants on.
+	g := parseMap(t, "1\n##start\ns 0 0\na 1 0\nb 1 1\nc 2 1\nd 3 1\n##end\ne 4 0\ns-a\na-e\ns-b\nb-c\nc-d\nd-e\n", utils.ParseOptions{})
+	points, breaks, err := utils.Sweep(g, 1, 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(breaks) != 1 || breaks[0].Ants != 4 || breaks[0].Turns != 4 || len(breaks[0].Paths) != 2 {
+		t.Fatalf("got breakpoints %+v, want 2 paths from 4 ants in 4 turns", breaks)
+	}
+	for _, p := range points {
+		paths, turns := 1, p.Ants+1
+		if p.Ants >= 4 {
+			paths, turns = 2, (p.Ants+5)/2
+		}
+		if len(p.Paths) != paths || p.Turns != turns {
+			t.Errorf("%d ants: got %d paths and %d turns, want %d and %d", p.Ants, len(p.Paths), p.Turns, paths, turns)
+		}
+	}
+
+	// Short of the ant count from which a second path pays off, no
+	// breakpoint is found.
+	if _, breaks, _ := utils.Sweep(g, 1, 3); len(breaks) != 0 {
+		t.Errorf("got breakpoints %+v for up to 3 ants, want none", breaks)
+	}
+
+	// Checkpoint queues and closures do not follow from path lengths.
+	for _, data := range []string{
+		"2\n##start\ns 0 0\n##checkpoint\nc 1 0\n##end\ne 2 0\ns-c\nc-e\n",
+		"2\n##start\ns 0 0\n##closed 1-3\nc 1 0\n##end\ne 2 0\ns-c\nc-e\n",
+	} {
+		g := parseMap(t, data, utils.ParseOptions{})
+		if _, _, err := utils.Sweep(g, 1, 5); err == nil {
+			t.Errorf("sweep accepted %q", data)
+		}
+		if _, _, err := utils.MaxAntsForTurns(g, 5); err == nil {
+			t.Errorf("max ants for turns accepted %q", data)
+		}
+	}
+}
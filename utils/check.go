@@ -0,0 +1,161 @@
+package utils
+
+import (
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// CheckMoves verifies that moves, one line per turn as printed by the
+// simulator, bring every ant of g from its start room to its end room
+// through the checkpoints in order, while respecting the tunnels, their
+// lengths, widths and closures, and the capacity and closures of the
+// rooms, that slow ants move no more often than their speed allows and
+// that ants going opposite ways never meet inside a tunnel nor swap rooms
+// through a tunnel one ant wide. An ant crossing a long tunnel leaves its
+// room at least as many turns before the move as the tunnel takes it to
+// cross, and may wait inside it, as when the room ahead is closed or
+// full, as long as the tunnel holds no more ants than its sections do.
+// The earliest such departure is assumed.
+// Once in its end room an ant stays there, and it never passes through
+// the start or end rooms of other ants.
+func CheckMoves(g *Graph, moves []string) error {
+	fail := func(turn int, reason string) error {
+		return LemError{"ERROR: invalid solution", "turn " + strconv.Itoa(turn) + ": " + reason}
+	}
+	var room []*Room
+	ants := g.startAnts()
+	for i, s := range g.starts() {
+		for range ants[i] {
+			room = append(room, s)
+		}
+	}
+	home := append([]*Room{}, room...)
+	since := make([]int, len(room))
+	passed := make([]int, len(room))
+	speeds := g.antSpeeds()
+	if speeds == nil {
+		speeds = slices.Repeat([]int{1}, len(room))
+	}
+	ids := make(map[string]int, len(room))
+	for id := 1; id <= len(room); id++ {
+		ids[g.AntLabel(id)] = id
+	}
+	// held[r][t] is the change in the number of ants in r from turn t on.
+	held := map[*Room][]int{}
+	entered := map[[2]*Room]map[int]int{}
+	// inside counts, per long tunnel and direction, the ants in it during
+	// each turn.
+	inside := map[[2]*Room]map[int]int{}
+
+	for i, line := range moves {
+		turn := i + 1
+		moved := map[int]bool{}
+		for _, m := range strings.Fields(line) {
+			ant, name, ok := strings.Cut(strings.TrimPrefix(m, "L"), "-")
+			if !ok || !strings.HasPrefix(m, "L") {
+				return fail(turn, "invalid move '"+m+"'")
+			}
+			id, ok := ids[ant]
+			if !ok {
+				return fail(turn, "unknown ant L"+ant)
+			}
+			if moved[id] {
+				return fail(turn, "ant L"+ant+" moves twice")
+			}
+			moved[id] = true
+			cur, next := room[id-1], g.Rooms[name]
+			if next == nil || !hasNeighbor(cur, next) {
+				return fail(turn, "no tunnel from "+cur.Name+" to '"+name+"' for ant L"+ant)
+			}
+			if g.endFor(home[id-1], cur) {
+				return fail(turn, "ant L"+ant+" leaves its end room "+cur.Name)
+			}
+			if (g.isStart(next) || g.isEnd(next)) && next != home[id-1] && !g.endFor(home[id-1], next) {
+				return fail(turn, "ant L"+ant+" passes through "+next.Name+", the start or end of other ants")
+			}
+			n, l, w := speeds[id-1], cur.LinkLength(next), cur.LinkWidth(next)
+			last := turn - (l-1)*n
+			if last-since[id-1] < n {
+				return fail(turn, "ant L"+ant+" cannot cross "+linkKey(cur.Name, next.Name)+" in time")
+			}
+			if closedAt(next.Closed, turn) {
+				return fail(turn, "ant L"+ant+" enters "+next.Name+" while it is closed")
+			}
+			t, way := tunnelOf(cur, next), [2]*Room{cur, next}
+			if entered[t] == nil {
+				entered[t] = map[int]int{}
+			}
+			// blocked tells why the ant cannot have entered the tunnel at
+			// turn d and stayed inside until it arrives.
+			blocked := func(d int) (int, string) {
+				if cur.linkClosedAt(next, d) {
+					return d, "ant L" + ant + " enters " + linkKey(cur.Name, next.Name) + " while it is closed"
+				}
+				if entered[t][d] >= w {
+					return d, "too many ants enter " + linkKey(cur.Name, next.Name)
+				}
+				for k := d; k < turn; k++ {
+					if inside[[2]*Room{next, cur}][k] > 0 {
+						return k, "ants cross " + linkKey(cur.Name, next.Name) + " in opposite directions"
+					}
+					if inside[way][k] >= (l-1)*w {
+						return k, "too many ants inside " + linkKey(cur.Name, next.Name)
+					}
+				}
+				return 0, ""
+			}
+			// The ant leaves at the earliest turn it can, possibly waiting
+			// inside a long tunnel as the simulator lets it.
+			dep := last
+			if l > 1 {
+				for d := since[id-1] + n; d < last; d++ {
+					if _, why := blocked(d); why == "" {
+						dep = d
+						break
+					}
+				}
+			}
+			if at, why := blocked(dep); why != "" {
+				return fail(at, why)
+			}
+			entered[t][dep]++
+			if inside[way] == nil {
+				inside[way] = map[int]int{}
+			}
+			for k := dep; k < turn; k++ {
+				inside[way][k]++
+			}
+			if !g.isStart(cur) && !g.isEnd(cur) {
+				if held[cur] == nil {
+					held[cur] = make([]int, len(moves)+2)
+				}
+				held[cur][since[id-1]]++
+				held[cur][dep]--
+			}
+			if cp := slices.Index(g.Checkpoints, next); cp > passed[id-1] {
+				return fail(turn, "ant L"+ant+" reaches checkpoint "+next.Name+" before "+g.Checkpoints[passed[id-1]].Name)
+			} else if cp == passed[id-1] {
+				passed[id-1]++
+			}
+			room[id-1], since[id-1] = next, turn
+		}
+	}
+	for id, r := range room {
+		if !g.endFor(home[id], r) {
+			return fail(len(moves), "ant L"+g.AntLabel(id+1)+" is in "+r.Name+", not in its end room")
+		}
+		if passed[id] < len(g.Checkpoints) {
+			return fail(len(moves), "ant L"+g.AntLabel(id+1)+" missed checkpoint "+g.Checkpoints[passed[id]].Name)
+		}
+	}
+	for r, diff := range held {
+		n := 0
+		for t, d := range diff {
+			if n += d; n > r.capacity() {
+				return fail(t, "room "+r.Name+" holds "+strconv.Itoa(n)+" ants")
+			}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,95 @@
+package utils
+
+import (
+	"slices"
+	"strconv"
+)
+
+// Bottleneck describes one room of the minimum start-end vertex cut and
+// how the turn count would change if ants had a second way around it.
+type Bottleneck struct {
+	Room        string `json:"room"`
+	Turns       int    `json:"turns"`
+	BypassTurns int    `json:"bypass_turns"`
+}
+
+// MinVertexCut returns a smallest set of rooms whose removal disconnects
+// start from end, in name order. A direct start-end tunnel cannot be cut
+// by removing rooms and is therefore not represented.
+func MinVertexCut(g *Graph) []*Room {
+	if g.Start == nil || g.End == nil {
+		return nil
+	}
+	rooms, idx := roomIndex(g)
+	f, _ := vertexNet(g, rooms, idx)
+	s := 2*idx[g.Start] + 1
+	f.maxFlow(s, 2*idx[g.End], len(rooms))
+	reach := f.reachable(s)
+	var cut []*Room
+	for i, r := range rooms {
+		if reach[2*i] && !reach[2*i+1] {
+			cut = append(cut, r)
+		}
+	}
+	return cut
+}
+
+// Bottlenecks reports, for every room of the minimum vertex cut, the
+// turns FindPaths achieves today and with a twin room that shares all of
+// the bottleneck's tunnels.
+func Bottlenecks(g *Graph) []Bottleneck {
+	turns := PathTurns(g.Ants, FindPaths(g))
+	var res []Bottleneck
+	for _, r := range MinVertexCut(g) {
+		c := g.Clone()
+		addTwin(c, c.Rooms[r.Name])
+		res = append(res, Bottleneck{
+			Room:        r.Name,
+			Turns:       turns,
+			BypassTurns: PathTurns(c.Ants, FindPaths(c)),
+		})
+	}
+	return res
+}
+
+// addTwin adds a copy of r linked to the same rooms through tunnels like
+// r's, placed on the first free coordinates to the right of the colony.
+func addTwin(g *Graph, r *Room) *Room {
+	x := r.X
+	for _, o := range g.Rooms {
+		if o.X > x {
+			x = o.X
+		}
+	}
+	name := r.Name + "_twin"
+	for i := 2; g.Rooms[name] != nil; i++ {
+		name = r.Name + "_twin" + strconv.Itoa(i)
+	}
+	twin, _ := g.AddRoom(name, x+1, r.Y)
+	twin.Capacity = r.Capacity
+	twin.Closed = slices.Clone(r.Closed)
+	// Copy the tunnels r leaves through first, in order, then those that
+	// only lead into it.
+	rooms, _ := roomIndex(g)
+	for _, nb := range slices.Concat(r.Links, rooms) {
+		if linked(twin, nb) || !linked(r, nb) {
+			continue
+		}
+		g.AddLink(twin.Name, nb.Name)
+		// The twin's far placement must not change the length AddLink
+		// derives from coordinates, so r's length is always copied.
+		g.SetLength(twin.Name, nb.Name, r.LinkLength(nb))
+		if w := r.LinkWidth(nb); w > 1 {
+			g.SetWidth(twin.Name, nb.Name, w)
+		}
+		for _, w := range r.Tunnels[nb].Closed {
+			g.CloseLink(twin.Name, nb.Name, w)
+		}
+		if !hasNeighbor(nb, r) {
+			g.SetOneWay(twin.Name, nb.Name)
+		} else if !hasNeighbor(r, nb) {
+			g.SetOneWay(nb.Name, twin.Name)
+		}
+	}
+	return twin
+}
@@ -0,0 +1,83 @@
+package utils
+
+import "sort"
+
+// LateAnt is an ant that reaches end after its deadline.
+type LateAnt struct {
+	Ant      string
+	Deadline int
+	Arrival  int
+	// Infeasible is set when no schedule brings all the ants due by then
+	// to end in time, whatever paths they take.
+	Infeasible bool
+}
+
+// LateAnts simulates the ants of g along paths, distributed for obj, and
+// lists those that miss their deadline, ant 1 first.
+func LateAnts(g *Graph, paths [][]*Room, obj Objective) []LateAnt {
+	if len(g.Deadlines) == 0 || len(paths) == 0 {
+		return nil
+	}
+	arrivals := make([]int, g.Ants)
+	for t, turn := range simulate(g, paths, routeAnts(g, paths, obj)) {
+		for _, m := range turn {
+			if g.isEnd(m.Room) {
+				arrivals[m.Ant-1] = t + 1
+			}
+		}
+	}
+	infeasible := map[int]bool{}
+	var late []LateAnt
+	for i, d := range antDeadlines(g, arrivals) {
+		if d == 0 || arrivals[i] <= d {
+			continue
+		}
+		if _, ok := infeasible[d]; !ok {
+			due := 0
+			for _, o := range g.Deadlines {
+				if o.Turn <= d {
+					due += o.Ants
+				}
+			}
+			most, _ := maxAntsBound(g, d)
+			infeasible[d] = due > most
+		}
+		late = append(late, LateAnt{Ant: g.AntLabel(i + 1), Deadline: d, Arrival: arrivals[i], Infeasible: infeasible[d]})
+	}
+	return late
+}
+
+// antDeadlines hands the deadlines of g to its ants, arrivals[i] being
+// the arrival turn of ant i+1, and returns the deadline of each ant, 0 for
+// none. The most urgent deadline that can still be met goes to the ant
+// arriving first; deadlines that cannot be met go to the ants arriving
+// last, so that they do not take the places of ants that can be on time.
+func antDeadlines(g *Graph, arrivals []int) []int {
+	ids := make([]int, len(arrivals))
+	for i := range ids {
+		ids[i] = i
+	}
+	sort.SliceStable(ids, func(i, j int) bool { return arrivals[ids[i]] < arrivals[ids[j]] })
+	var turns []int
+	for _, d := range g.Deadlines {
+		for range d.Ants {
+			turns = append(turns, d.Turn)
+		}
+	}
+	sort.Ints(turns)
+	res := make([]int, len(arrivals))
+	next := 0
+	var missed []int
+	for _, d := range turns {
+		if next < len(ids) && arrivals[ids[next]] <= d {
+			res[ids[next]] = d
+			next++
+		} else {
+			missed = append(missed, d)
+		}
+	}
+	for i, d := range missed {
+		res[ids[len(ids)-len(missed)+i]] = d
+	}
+	return res
+}
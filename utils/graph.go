@@ -0,0 +1,697 @@
+package utils
+
+import (
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// NewGraph returns an empty colony holding the given number of ants.
+func NewGraph(ants int) *Graph {
+	return &Graph{Ants: ants, Rooms: make(map[string]*Room), coords: make(map[[2]int]*Room)}
+}
+
+// AddRoom creates a room, rejecting the names and coordinates the parser rejects.
+func (g *Graph) AddRoom(name string, x, y int) (*Room, error) {
+	if !validRoomName(name) {
+		return nil, LemError{"ERROR: invalid data format", "invalid room name '" + name + "'"}
+	}
+	if _, ok := g.Rooms[name]; ok {
+		return nil, LemError{"ERROR: invalid data format", "duplicate room name '" + name + "'"}
+	}
+	if g.coords == nil {
+		g.coords = map[[2]int]*Room{}
+		for _, r := range g.Rooms {
+			g.coords[[2]int{r.X, r.Y}] = r
+		}
+	}
+	if _, ok := g.coords[[2]int{x, y}]; ok {
+		return nil, LemError{"ERROR: invalid data format", "duplicate coordinates " + strconv.Itoa(x) + " " + strconv.Itoa(y)}
+	}
+	r := &Room{Name: name, X: x, Y: y}
+	g.Rooms[name] = r
+	g.coords[[2]int{x, y}] = r
+	return r, nil
+}
+
+// AddLink joins two existing rooms with a tunnel.
+func (g *Graph) AddLink(a, b string) error {
+	if a == b {
+		return LemError{"ERROR: invalid data format", "self-loop link " + a + "-" + b}
+	}
+	ra, rb, err := g.linkRooms(a, b)
+	if err != nil {
+		return err
+	}
+	if linked(ra, rb) {
+		return LemError{"ERROR: invalid data format", "duplicate link " + linkKey(a, b)}
+	}
+	ra.Links = append(ra.Links, rb)
+	rb.Links = append(rb.Links, ra)
+	if l := turnsApart(ra, rb); g.Euclid && l > 1 {
+		setTunnel(ra, rb, func(t *Tunnel) { t.Length = l })
+		setTunnel(rb, ra, func(t *Tunnel) { t.Length = l })
+	}
+	return nil
+}
+
+// RemoveLink deletes the tunnel between two rooms.
+func (g *Graph) RemoveLink(a, b string) error {
+	ra, rb, err := g.linkRooms(a, b)
+	if err != nil {
+		return err
+	}
+	if !linked(ra, rb) {
+		return LemError{"ERROR: invalid data format", "unknown link " + linkKey(a, b)}
+	}
+	ra.Links = withoutRoom(ra.Links, rb)
+	rb.Links = withoutRoom(rb.Links, ra)
+	delete(ra.Tunnels, rb)
+	delete(rb.Tunnels, ra)
+	return nil
+}
+
+// SetLength sets the number of turns needed to cross the tunnel between
+// two rooms.
+func (g *Graph) SetLength(a, b string, turns int) error {
+	ra, rb, err := g.linkRooms(a, b)
+	if err != nil {
+		return err
+	}
+	if !linked(ra, rb) {
+		return LemError{"ERROR: invalid data format", "unknown link " + linkKey(a, b)}
+	}
+	if turns < 1 {
+		return LemError{"ERROR: invalid data format", "invalid length for link " + linkKey(a, b)}
+	}
+	setTunnel(ra, rb, func(t *Tunnel) { t.Length = turns })
+	setTunnel(rb, ra, func(t *Tunnel) { t.Length = turns })
+	return nil
+}
+
+// SetOneWay makes the tunnel between two rooms passable only from a to b.
+func (g *Graph) SetOneWay(a, b string) error {
+	ra, rb, err := g.linkRooms(a, b)
+	if err != nil {
+		return err
+	}
+	if !linked(ra, rb) {
+		return LemError{"ERROR: invalid data format", "unknown link " + linkKey(a, b)}
+	}
+	if !hasNeighbor(ra, rb) {
+		ra.Links = append(ra.Links, rb)
+	}
+	rb.Links = withoutRoom(rb.Links, ra)
+	return nil
+}
+
+// RemoveRoom deletes a room together with every tunnel touching it.
+func (g *Graph) RemoveRoom(name string) error {
+	r, ok := g.Rooms[name]
+	if !ok {
+		return LemError{"ERROR: invalid data format", "unknown room '" + name + "'"}
+	}
+	for _, o := range g.Rooms {
+		o.Links = withoutRoom(o.Links, r)
+		delete(o.Tunnels, r)
+	}
+	r.Links = nil
+	r.Tunnels = nil
+	delete(g.Rooms, name)
+	if g.coords[[2]int{r.X, r.Y}] == r {
+		delete(g.coords, [2]int{r.X, r.Y})
+	}
+	for i := len(g.Starts) - 1; i >= 0; i-- {
+		if g.Starts[i] == r {
+			g.Starts = slices.Delete(g.Starts, i, i+1)
+			g.StartAnts = slices.Delete(g.StartAnts, i, i+1)
+		}
+	}
+	g.Ends = withoutRoom(g.Ends, r)
+	g.Colonies = slices.DeleteFunc(g.Colonies, func(c Colony) bool { return c.Start == r || c.End == r })
+	g.Checkpoints = withoutRoom(g.Checkpoints, r)
+	if g.Start == r {
+		g.Start = nil
+		if len(g.Starts) > 0 {
+			g.Start = g.Starts[0]
+		}
+	}
+	if g.End == r {
+		g.End = nil
+		if len(g.Ends) > 0 {
+			g.End = g.Ends[0]
+		}
+	}
+	return nil
+}
+
+// SetWidth sets how many ants may enter the tunnel between two rooms in a
+// single turn.
+func (g *Graph) SetWidth(a, b string, ants int) error {
+	ra, rb, err := g.linkRooms(a, b)
+	if err != nil {
+		return err
+	}
+	if !linked(ra, rb) {
+		return LemError{"ERROR: invalid data format", "unknown link " + linkKey(a, b)}
+	}
+	if ants < 1 {
+		return LemError{"ERROR: invalid data format", "invalid width for link " + linkKey(a, b)}
+	}
+	setTunnel(ra, rb, func(t *Tunnel) { t.Width = ants })
+	setTunnel(rb, ra, func(t *Tunnel) { t.Width = ants })
+	return nil
+}
+
+// CloseLink keeps ants from entering the tunnel between two rooms, in
+// either direction, during the turns of w.
+func (g *Graph) CloseLink(a, b string, w Window) error {
+	ra, rb, err := g.linkRooms(a, b)
+	if err != nil {
+		return err
+	}
+	if !linked(ra, rb) {
+		return LemError{"ERROR: invalid data format", "unknown link " + linkKey(a, b)}
+	}
+	if w.From < 1 || w.To < w.From {
+		return LemError{"ERROR: invalid data format", "invalid closure for link " + linkKey(a, b)}
+	}
+	setTunnel(ra, rb, func(t *Tunnel) { t.Closed = append(slices.Clip(t.Closed), w) })
+	setTunnel(rb, ra, func(t *Tunnel) { t.Closed = append(slices.Clip(t.Closed), w) })
+	return nil
+}
+
+// CloseRoom keeps ants from entering a room during the turns of w.
+func (g *Graph) CloseRoom(name string, w Window) error {
+	r, ok := g.Rooms[name]
+	if !ok {
+		return LemError{"ERROR: invalid data format", "unknown room '" + name + "'"}
+	}
+	if w.From < 1 || w.To < w.From {
+		return LemError{"ERROR: invalid data format", "invalid closure for room '" + name + "'"}
+	}
+	r.Closed = append(r.Closed, w)
+	return nil
+}
+
+// SetCapacity sets how many ants a room holds at once.
+func (g *Graph) SetCapacity(name string, ants int) error {
+	r, ok := g.Rooms[name]
+	if !ok {
+		return LemError{"ERROR: invalid data format", "unknown room '" + name + "'"}
+	}
+	if ants < 1 {
+		return LemError{"ERROR: invalid data format", "invalid capacity for room '" + name + "'"}
+	}
+	r.Capacity = ants
+	return nil
+}
+
+// SetStart marks an existing room as ##start.
+func (g *Graph) SetStart(name string) error {
+	r, ok := g.Rooms[name]
+	if !ok {
+		return LemError{"ERROR: invalid data format", "unknown room '" + name + "'"}
+	}
+	g.Start = r
+	return nil
+}
+
+// SetEnd marks an existing room as ##end.
+func (g *Graph) SetEnd(name string) error {
+	r, ok := g.Rooms[name]
+	if !ok {
+		return LemError{"ERROR: invalid data format", "unknown room '" + name + "'"}
+	}
+	g.End = r
+	return nil
+}
+
+// AddStart marks one more room as a start room holding ants of its own.
+// The ants of every start room make up g.Ants.
+func (g *Graph) AddStart(name string, ants int) error {
+	r, ok := g.Rooms[name]
+	if !ok {
+		return LemError{"ERROR: invalid data format", "unknown room '" + name + "'"}
+	}
+	if g.isStart(r) || g.isEnd(r) {
+		return LemError{"ERROR: invalid data format", "room '" + name + "' is already a start or end"}
+	}
+	if ants < 0 {
+		return LemError{"ERROR: invalid data format", "invalid ants count for start '" + name + "'"}
+	}
+	if len(g.Starts) == 0 {
+		g.Start = r
+	}
+	g.Starts = append(g.Starts, r)
+	g.StartAnts = append(g.StartAnts, ants)
+	return nil
+}
+
+// AddEnd marks one more room as an end room.
+func (g *Graph) AddEnd(name string) error {
+	r, ok := g.Rooms[name]
+	if !ok {
+		return LemError{"ERROR: invalid data format", "unknown room '" + name + "'"}
+	}
+	if g.isStart(r) || g.isEnd(r) {
+		return LemError{"ERROR: invalid data format", "room '" + name + "' is already a start or end"}
+	}
+	if len(g.Ends) == 0 {
+		g.End = r
+	}
+	g.Ends = append(g.Ends, r)
+	return nil
+}
+
+// AddColony adds a group of ants travelling from start to end. Every
+// colony has a start room of its own; end rooms may be shared. The ants
+// of every colony make up g.Ants.
+func (g *Graph) AddColony(name, start, end string, ants int) error {
+	if !validColonyName(name) {
+		return LemError{"ERROR: invalid data format", "invalid colony name '" + name + "'"}
+	}
+	rs, re, err := g.linkRooms(start, end)
+	if err != nil {
+		return err
+	}
+	for _, c := range g.Colonies {
+		if c.Name == name {
+			return LemError{"ERROR: invalid data format", "duplicate colony '" + name + "'"}
+		}
+		if c.Start == rs {
+			return LemError{"ERROR: invalid data format", "colonies " + c.Name + " and " + name + " overlap at a start room"}
+		}
+	}
+	if rs == re || ants < 0 {
+		return LemError{"ERROR: invalid data format", "invalid colony '" + name + "'"}
+	}
+	if len(g.Colonies) == 0 {
+		g.Start, g.End = rs, re
+	}
+	g.Colonies = append(g.Colonies, Colony{Name: name, Start: rs, End: re, Ants: ants})
+	return nil
+}
+
+// AddCheckpoint appends a room to those every ant must pass through, in
+// the order they are added.
+func (g *Graph) AddCheckpoint(name string) error {
+	r, ok := g.Rooms[name]
+	if !ok {
+		return LemError{"ERROR: invalid data format", "unknown room '" + name + "'"}
+	}
+	if slices.Contains(g.Checkpoints, r) {
+		return LemError{"ERROR: invalid data format", "duplicate checkpoint '" + name + "'"}
+	}
+	g.Checkpoints = append(g.Checkpoints, r)
+	return nil
+}
+
+// AddSpeedClass makes ants of g move at most once every every turns. Ants
+// are numbered fastest first: those moving every turn, then the classes
+// from the fastest on. Classes are only allowed with a single start room.
+func (g *Graph) AddSpeedClass(every, ants int) error {
+	if every < 1 || ants < 1 {
+		return LemError{"ERROR: invalid data format", "invalid speed class"}
+	}
+	if len(g.Starts) > 0 || len(g.Colonies) > 0 || len(g.Deadlines) > 0 {
+		return LemError{"ERROR: invalid data format", "speed classes need a single start room and no deadlines"}
+	}
+	total := ants
+	for _, c := range g.Speeds {
+		total += c.Ants
+	}
+	if total > g.Ants {
+		return LemError{"ERROR: invalid data format", "speed classes hold more than " + strconv.Itoa(g.Ants) + " ants"}
+	}
+	g.Speeds = append(g.Speeds, SpeedClass{Every: every, Ants: ants})
+	return nil
+}
+
+// antSpeeds returns how many turns each ant of g takes per move, ant 1
+// first, or nil when every ant moves every turn.
+func (g *Graph) antSpeeds() []int {
+	if len(g.Speeds) == 0 {
+		return nil
+	}
+	speeds := make([]int, 0, g.Ants)
+	for _, c := range g.Speeds {
+		for range c.Ants {
+			speeds = append(speeds, c.Every)
+		}
+	}
+	for len(speeds) < g.Ants {
+		speeds = append(speeds, 1)
+	}
+	slices.Sort(speeds)
+	return speeds[:g.Ants]
+}
+
+// AddDeadline asks for ants of g to reach end by turn. The ants are alike,
+// so deadlines go to ants only once their arrivals are known, see
+// antDeadlines. Deadlines are only allowed with a single start room and
+// without speed classes.
+func (g *Graph) AddDeadline(turn, ants int) error {
+	if turn < 1 || ants < 1 {
+		return LemError{"ERROR: invalid data format", "invalid deadline"}
+	}
+	if len(g.Starts) > 0 || len(g.Colonies) > 0 || len(g.Speeds) > 0 {
+		return LemError{"ERROR: invalid data format", "deadlines need a single start room and no speed classes"}
+	}
+	total := ants
+	for _, d := range g.Deadlines {
+		total += d.Ants
+	}
+	if total > g.Ants {
+		return LemError{"ERROR: invalid data format", "deadlines hold more than " + strconv.Itoa(g.Ants) + " ants"}
+	}
+	g.Deadlines = append(g.Deadlines, Deadline{Turn: turn, Ants: ants})
+	return nil
+}
+
+// AntLabel names ant id, numbered from 1 across all colonies, as it is
+// printed after the L of a move: its number, or its colony name and its
+// number within the colony.
+func (g *Graph) AntLabel(id int) string {
+	for _, c := range g.Colonies {
+		if id <= c.Ants {
+			return c.Name + strconv.Itoa(id)
+		}
+		id -= c.Ants
+	}
+	return strconv.Itoa(id)
+}
+
+// starts returns every start room.
+func (g *Graph) starts() []*Room {
+	if len(g.Colonies) > 0 {
+		rooms := make([]*Room, len(g.Colonies))
+		for i, c := range g.Colonies {
+			rooms[i] = c.Start
+		}
+		return rooms
+	}
+	if len(g.Starts) > 0 {
+		return g.Starts
+	}
+	return []*Room{g.Start}
+}
+
+// startAnts returns the number of ants in each start room.
+func (g *Graph) startAnts() []int {
+	if len(g.Colonies) > 0 {
+		ants := make([]int, len(g.Colonies))
+		for i, c := range g.Colonies {
+			ants[i] = c.Ants
+		}
+		return ants
+	}
+	if len(g.Starts) > 0 {
+		return g.StartAnts
+	}
+	return []int{g.Ants}
+}
+
+// ends returns every end room.
+func (g *Graph) ends() []*Room {
+	if len(g.Colonies) > 0 {
+		var rooms []*Room
+		for _, c := range g.Colonies {
+			if !slices.Contains(rooms, c.End) {
+				rooms = append(rooms, c.End)
+			}
+		}
+		return rooms
+	}
+	if len(g.Ends) > 0 {
+		return g.Ends
+	}
+	return []*Room{g.End}
+}
+
+func (g *Graph) isStart(r *Room) bool {
+	return r == g.Start || slices.Contains(g.Starts, r) || slices.ContainsFunc(g.Colonies, func(c Colony) bool { return c.Start == r })
+}
+
+func (g *Graph) isEnd(r *Room) bool {
+	return r == g.End || slices.Contains(g.Ends, r) || slices.ContainsFunc(g.Colonies, func(c Colony) bool { return c.End == r })
+}
+
+// hasClosures reports whether some room or tunnel of g is ever closed.
+func (g *Graph) hasClosures() bool {
+	for _, r := range g.Rooms {
+		if len(r.Closed) > 0 {
+			return true
+		}
+		for _, t := range r.Tunnels {
+			if len(t.Closed) > 0 {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// endFor reports whether r is a destination of the ants leaving start:
+// the end room of start's colony, or any end room without colonies.
+func (g *Graph) endFor(start, r *Room) bool {
+	for _, c := range g.Colonies {
+		if c.Start == start {
+			return c.End == r
+		}
+	}
+	return g.isEnd(r)
+}
+
+// Neighbors returns the rooms an ant in name can move to, or nil if it
+// does not exist.
+func (g *Graph) Neighbors(name string) []*Room {
+	r, ok := g.Rooms[name]
+	if !ok {
+		return nil
+	}
+	return append([]*Room{}, r.Links...)
+}
+
+// Validate checks that the colony satisfies every rule ParseInput enforces.
+func (g *Graph) Validate() error {
+	if g.Ants <= 0 {
+		return LemError{"ERROR: invalid data format", "invalid ants count"}
+	}
+	if g.Ants > MaxAnts {
+		return LemError{"ERROR: ant limit exceeded", "ant count greater than 100000"}
+	}
+	if g.Start == nil || g.End == nil || g.Rooms[g.Start.Name] != g.Start || g.Rooms[g.End.Name] != g.End {
+		return LemError{"ERROR: invalid data format", "missing start or end"}
+	}
+	if len(g.Starts) > 0 {
+		total := 0
+		for i, r := range g.Starts {
+			if g.Rooms[r.Name] != r || i >= len(g.StartAnts) || g.StartAnts[i] < 0 {
+				return LemError{"ERROR: invalid data format", "invalid start '" + r.Name + "'"}
+			}
+			total += g.StartAnts[i]
+		}
+		if g.Starts[0] != g.Start || len(g.StartAnts) != len(g.Starts) || total != g.Ants {
+			return LemError{"ERROR: invalid data format", "start ant counts do not add up to " + strconv.Itoa(g.Ants)}
+		}
+	}
+	for _, r := range g.Ends {
+		if g.Rooms[r.Name] != r || g.Ends[0] != g.End {
+			return LemError{"ERROR: invalid data format", "invalid end '" + r.Name + "'"}
+		}
+	}
+	for i, r := range g.Checkpoints {
+		if g.Rooms[r.Name] != r || g.isStart(r) || g.isEnd(r) || slices.Contains(g.Checkpoints[:i], r) {
+			return LemError{"ERROR: invalid data format", "invalid checkpoint '" + r.Name + "'"}
+		}
+	}
+	if len(g.Speeds) > 0 {
+		total := 0
+		for _, c := range g.Speeds {
+			if c.Every < 1 || c.Ants < 1 {
+				return LemError{"ERROR: invalid data format", "invalid speed class"}
+			}
+			total += c.Ants
+		}
+		if total > g.Ants || len(g.Starts) > 0 || len(g.Colonies) > 0 {
+			return LemError{"ERROR: invalid data format", "invalid speed classes"}
+		}
+	}
+	if len(g.Deadlines) > 0 {
+		total := 0
+		for _, d := range g.Deadlines {
+			if d.Turn < 1 || d.Ants < 1 {
+				return LemError{"ERROR: invalid data format", "invalid deadline"}
+			}
+			total += d.Ants
+		}
+		if total > g.Ants || len(g.Starts) > 0 || len(g.Colonies) > 0 || len(g.Speeds) > 0 {
+			return LemError{"ERROR: invalid data format", "invalid deadlines"}
+		}
+	}
+	if len(g.Colonies) > 0 {
+		total := 0
+		for _, c := range g.Colonies {
+			if !validColonyName(c.Name) || c.Ants < 0 || g.Rooms[c.Start.Name] != c.Start || g.Rooms[c.End.Name] != c.End {
+				return LemError{"ERROR: invalid data format", "invalid colony '" + c.Name + "'"}
+			}
+			total += c.Ants
+		}
+		if total != g.Ants {
+			return LemError{"ERROR: invalid data format", "colony ant counts do not add up to " + strconv.Itoa(g.Ants)}
+		}
+	}
+	coords := map[[2]int]bool{}
+	for name, r := range g.Rooms {
+		if name != r.Name || !validRoomName(name) {
+			return LemError{"ERROR: invalid data format", "invalid room name '" + name + "'"}
+		}
+		if coords[[2]int{r.X, r.Y}] {
+			return LemError{"ERROR: invalid data format", "duplicate coordinates " + strconv.Itoa(r.X) + " " + strconv.Itoa(r.Y)}
+		}
+		coords[[2]int{r.X, r.Y}] = true
+		if r.Capacity < 0 {
+			return LemError{"ERROR: invalid data format", "invalid capacity for room '" + name + "'"}
+		}
+		for _, w := range r.Closed {
+			if w.From < 1 || w.To < w.From {
+				return LemError{"ERROR: invalid data format", "invalid closure for room '" + name + "'"}
+			}
+		}
+		seen := map[*Room]bool{}
+		for _, nb := range r.Links {
+			if nb == r {
+				return LemError{"ERROR: invalid data format", "self-loop link " + name + "-" + name}
+			}
+			if seen[nb] {
+				return LemError{"ERROR: invalid data format", "duplicate link " + linkKey(name, nb.Name)}
+			}
+			seen[nb] = true
+			if g.Rooms[nb.Name] != nb {
+				return LemError{"ERROR: invalid data format", "unknown room in link '" + nb.Name + "'"}
+			}
+			if r.Tunnels[nb].Length < 0 {
+				return LemError{"ERROR: invalid data format", "invalid length for link " + linkKey(name, nb.Name)}
+			}
+			if r.Tunnels[nb].Width < 0 {
+				return LemError{"ERROR: invalid data format", "invalid width for link " + linkKey(name, nb.Name)}
+			}
+			for _, w := range r.Tunnels[nb].Closed {
+				if w.From < 1 || w.To < w.From {
+					return LemError{"ERROR: invalid data format", "invalid closure for link " + linkKey(name, nb.Name)}
+				}
+			}
+		}
+	}
+	return nil
+}
+
+func (g *Graph) linkRooms(a, b string) (*Room, *Room, error) {
+	ra, ok := g.Rooms[a]
+	if !ok {
+		return nil, nil, LemError{"ERROR: invalid data format", "unknown room in link '" + a + "'"}
+	}
+	rb, ok := g.Rooms[b]
+	if !ok {
+		return nil, nil, LemError{"ERROR: invalid data format", "unknown room in link '" + b + "'"}
+	}
+	return ra, rb, nil
+}
+
+// setTunnel edits the properties of the tunnel from r to nb.
+func setTunnel(r, nb *Room, edit func(*Tunnel)) {
+	if r.Tunnels == nil {
+		r.Tunnels = map[*Room]Tunnel{}
+	}
+	t := r.Tunnels[nb]
+	edit(&t)
+	r.Tunnels[nb] = t
+}
+
+// validColonyName accepts names made of letters only, so that a label
+// such as a12 splits unambiguously into colony and ant number.
+func validColonyName(name string) bool {
+	if name == "" {
+		return false
+	}
+	for _, c := range name {
+		if !('a' <= c && c <= 'z' || 'A' <= c && c <= 'Z') {
+			return false
+		}
+	}
+	return true
+}
+
+func validRoomName(name string) bool {
+	return name != "" && !strings.HasPrefix(name, "L") && !strings.HasPrefix(name, "#") && !strings.ContainsAny(name, " \t")
+}
+
+// linked reports whether a tunnel joins a and b in either direction.
+func linked(a, b *Room) bool {
+	return hasNeighbor(a, b) || hasNeighbor(b, a)
+}
+
+// linkKey names a tunnel independently of the order of its endpoints.
+func linkKey(a, b string) string {
+	if b < a {
+		a, b = b, a
+	}
+	return a + "-" + b
+}
+
+func withoutRoom(rooms []*Room, r *Room) []*Room {
+	out := rooms[:0]
+	for _, x := range rooms {
+		if x != r {
+			out = append(out, x)
+		}
+	}
+	return out
+}
+
+// Clone returns a deep copy of g that can be edited without affecting it.
+func (g *Graph) Clone() *Graph {
+	c := NewGraph(g.Ants)
+	for name, r := range g.Rooms {
+		c.Rooms[name] = &Room{Name: r.Name, X: r.X, Y: r.Y, Capacity: r.Capacity, Closed: slices.Clone(r.Closed)}
+		c.coords[[2]int{r.X, r.Y}] = c.Rooms[name]
+	}
+	for name, r := range g.Rooms {
+		cr := c.Rooms[name]
+		for _, nb := range r.Links {
+			cr.Links = append(cr.Links, c.Rooms[nb.Name])
+		}
+		for nb, t := range r.Tunnels {
+			if cr.Tunnels == nil {
+				cr.Tunnels = map[*Room]Tunnel{}
+			}
+			t.Closed = slices.Clone(t.Closed)
+			cr.Tunnels[c.Rooms[nb.Name]] = t
+		}
+	}
+	if g.Start != nil {
+		c.Start = c.Rooms[g.Start.Name]
+	}
+	if g.End != nil {
+		c.End = c.Rooms[g.End.Name]
+	}
+	for _, r := range g.Starts {
+		c.Starts = append(c.Starts, c.Rooms[r.Name])
+	}
+	c.StartAnts = append(c.StartAnts, g.StartAnts...)
+	for _, r := range g.Ends {
+		c.Ends = append(c.Ends, c.Rooms[r.Name])
+	}
+	for _, col := range g.Colonies {
+		col.Start, col.End = c.Rooms[col.Start.Name], c.Rooms[col.End.Name]
+		c.Colonies = append(c.Colonies, col)
+	}
+	for _, r := range g.Checkpoints {
+		c.Checkpoints = append(c.Checkpoints, c.Rooms[r.Name])
+	}
+	c.Speeds = slices.Clone(g.Speeds)
+	c.Deadlines = slices.Clone(g.Deadlines)
+	c.Euclid = g.Euclid
+	return c
+}
@@ -0,0 +1,142 @@
+package utils
+
+import (
+	"fmt"
+	"sort"
+)
+
+// Objective selects how FindPathsWith chooses among path sets that need
+// the same number of turns, and how ants are spread over the chosen paths.
+type Objective int
+
+const (
+	// ObjectiveNone keeps the first fastest set in path order.
+	ObjectiveNone Objective = iota
+	// ObjectiveMoves minimises the total number of ant moves. With long
+	// tunnels it minimises the turns ants spend in tunnels instead, which
+	// is the same on colonies where every tunnel takes one turn.
+	ObjectiveMoves
+	// ObjectiveArrival minimises the average arrival turn.
+	ObjectiveArrival
+	// ObjectivePaths minimises the number of paths used.
+	ObjectivePaths
+)
+
+var objectiveNames = map[string]Objective{
+	"none":    ObjectiveNone,
+	"moves":   ObjectiveMoves,
+	"arrival": ObjectiveArrival,
+	"paths":   ObjectivePaths,
+}
+
+// ParseObjective reads an objective name: none, moves, arrival or paths.
+func ParseObjective(name string) (Objective, error) {
+	obj, ok := objectiveNames[name]
+	if !ok {
+		return ObjectiveNone, fmt.Errorf("unknown objective %q (want none, moves, arrival or paths)", name)
+	}
+	return obj, nil
+}
+
+// Metrics measures a solution.
+type Metrics struct {
+	Turns int
+	// Moves counts every time an ant enters a room, as in the printed
+	// moves: a long tunnel is one move however many turns it takes.
+	Moves      int
+	AvgArrival float64
+	PathsUsed  int
+}
+
+// Evaluate computes the metrics of moving ants along paths with the
+// distribution chosen for obj.
+func Evaluate(ants int, paths [][]*Room, obj Objective) Metrics {
+	if len(paths) == 0 {
+		return Metrics{}
+	}
+	lengths := pathLengths(paths)
+	m := Metrics{Turns: ComputeTurns(ants, lengths)}
+	arrivals := 0
+	for i, c := range pathCounts(lengths, ants, obj) {
+		if c > 0 {
+			m.PathsUsed++
+		}
+		m.Moves += c * (len(paths[i]) - 1)
+		arrivals += arrivalSum(lengths[i], c)
+	}
+	m.AvgArrival = float64(arrivals) / float64(ants)
+	return m
+}
+
+// objectiveCost is the value obj minimises for a set of path lengths.
+func objectiveCost(obj Objective, lengths []int, ants int) int {
+	switch obj {
+	case ObjectiveMoves:
+		cost := 0
+		for i, c := range countsByMoves(lengths, ants) {
+			cost += c * lengths[i]
+		}
+		return cost
+	case ObjectiveArrival:
+		cost := 0
+		for i, c := range countsByArrival(lengths, ants) {
+			cost += arrivalSum(lengths[i], c)
+		}
+		return cost
+	case ObjectivePaths:
+		return len(lengths)
+	}
+	return 0
+}
+
+// arrivalSum adds up the arrival turns of c ants sent one per turn down a
+// path of length l: l, l+1, ..., l+c-1.
+func arrivalSum(l, c int) int {
+	return c*l + c*(c-1)/2
+}
+
+// countsByMoves fills the shortest paths first, each up to what it can
+// deliver within the minimum number of turns.
+func countsByMoves(lengths []int, ants int) []int {
+	t := ComputeTurns(ants, lengths)
+	order := make([]int, len(lengths))
+	for i := range order {
+		order[i] = i
+	}
+	sort.SliceStable(order, func(a, b int) bool { return lengths[order[a]] < lengths[order[b]] })
+	counts := make([]int, len(lengths))
+	left := ants
+	for _, i := range order {
+		c := t - lengths[i] + 1
+		if c > left {
+			c = left
+		}
+		if c > 0 {
+			counts[i] = c
+			left -= c
+		}
+	}
+	return counts
+}
+
+// countsByArrival gives each ant the earliest free arrival slot. Every
+// slot before turn t = ComputeTurns is taken and the remaining ants
+// arrive at turn t, which minimises the sum of arrival turns.
+func countsByArrival(lengths []int, ants int) []int {
+	t := ComputeTurns(ants, lengths)
+	counts := make([]int, len(lengths))
+	left := ants
+	for i, l := range lengths {
+		if t > l {
+			counts[i] = t - l
+			left -= t - l
+		}
+	}
+	for i, l := range lengths {
+		if left > 0 && l <= t {
+			counts[i]++
+			left--
+		}
+	}
+	return counts
+}
@@ -1,128 +1,752 @@
 package utils
 
-import "sort"
+import (
+	"slices"
+	"sort"
+	"strings"
+)
 
+// allPaths enumerates up to limit paths from each start room to one of
+// its end rooms, never passing through another start or end room and
+// passing through the checkpoints in order. Rooms and tunnels that close
+// for good are left out.
 func allPaths(g *Graph, limit int) [][]*Room {
 	var res [][]*Room
 	path := []*Room{}
 	visited := map[*Room]bool{}
+	found, passed := 0, 0
 	var dfs func(*Room)
 	dfs = func(r *Room) {
-		if len(res) >= limit {
+		if found >= limit {
 			return
 		}
-		if r == g.End {
-			p := append(append([]*Room{}, path...), r)
-			res = append(res, p)
+		if len(path) > 0 && g.endFor(path[0], r) {
+			if passed == len(g.Checkpoints) {
+				p := append(append([]*Room{}, path...), r)
+				res = append(res, p)
+				found++
+			}
+			return
+		}
+		cp := slices.Index(g.Checkpoints, r)
+		if cp >= 0 && cp != passed {
 			return
 		}
+		if cp >= 0 {
+			passed++
+		}
 		visited[r] = true
 		path = append(path, r)
 		for _, nb := range r.Links {
-			if !visited[nb] {
+			if !visited[nb] && (g.endFor(path[0], nb) || !g.isStart(nb) && !g.isEnd(nb)) &&
+				!forbidden(nb.Closed) && !forbidden(r.Tunnels[nb].Closed) {
 				dfs(nb)
 			}
 		}
 		path = path[:len(path)-1]
 		visited[r] = false
+		if cp >= 0 {
+			passed--
+		}
+	}
+	for _, s := range g.starts() {
+		found = 0
+		dfs(s)
 	}
-	dfs(g.Start)
 	return res
 }
 
-func bestDisjointPaths(all [][]*Room, ants int) [][]*Room {
+// bestDisjointPaths searches the subsets of all for the one moving the
+// ants of g in the fewest turns. Paths of the same colony never share
+// more than their rooms and tunnels admit, checkpoints apart. Paths of
+// different colonies may cross, and all paths meet at the checkpoints,
+// the simulator then making ants wait for each other, as long as they can
+// never wait on each other forever. Paths going opposite ways through
+// the same rooms or tunnels take turns through them. In those cases, when
+// rooms or tunnels close for a while and when some ants are slow, each
+// candidate is simulated to learn its actual number of turns.
+func bestDisjointPaths(g *Graph, all [][]*Room, obj Objective) [][]*Room {
 	bestTurns := int(^uint(0) >> 1)
+	bestCost := 0
 	var best [][]*Room
 	var bestIdx []int
-	var rec func(int, [][]*Room, []int, map[*Room]bool)
-	rec = func(i int, cur [][]*Room, idxs []int, used map[*Room]bool) {
+	group := func(p []*Room) int { return 0 }
+	used := []*pathUse{newPathUse()}
+	if len(g.Colonies) > 0 {
+		starts := g.starts()
+		group = func(p []*Room) int { return slices.Index(starts, p[0]) }
+		for range starts[1:] {
+			used = append(used, newPathUse())
+		}
+	}
+	for _, u := range used {
+		for _, r := range g.Checkpoints {
+			u.shared[r] = true
+		}
+	}
+	consider := func(t, cost int, cur [][]*Room, idxs []int) {
+		better := false
+		if t < bestTurns {
+			better = true
+		} else if t == bestTurns && cost != bestCost {
+			better = cost < bestCost
+		} else if t == bestTurns {
+			for k := 0; k < len(idxs) && k < len(bestIdx); k++ {
+				if idxs[k] < bestIdx[k] {
+					better = true
+					break
+				} else if idxs[k] > bestIdx[k] {
+					break
+				}
+			}
+			if !better && len(idxs) < len(bestIdx) {
+				better = true
+			}
+		}
+		if better {
+			bestTurns = t
+			bestCost = cost
+			best = append([][]*Room{}, cur...)
+			bestIdx = append([]int{}, idxs...)
+		}
+	}
+	// Candidates that must be simulated are kept with their lower bound
+	// and simulated from the most promising on, until the bound alone
+	// rules out the rest.
+	type candidate struct {
+		bound, cost int
+		paths       [][]*Room
+		idxs        []int
+	}
+	var candidates []candidate
+	copies := make([]int, len(all))
+	crossing := len(g.Colonies) > 0 || len(g.Checkpoints) > 0 || g.hasClosures() || len(g.Speeds) > 0
+	// alone[i] tells whether no later path of the same group competes with
+	// all[i] for a room or tunnel. Such a path takes every copy that fits
+	// at once rather than one more per level of the search, and the
+	// copies a solution does not need are dropped once it is complete.
+	alone := make([]bool, len(all))
+	for i, p := range all {
+		alone[i] = true
+		for _, q := range all[i+1:] {
+			if group(p) == group(q) && used[group(p)].competes(p, q) {
+				alone[i] = false
+				break
+			}
+		}
+	}
+	var rec func(int, [][]*Room, []int)
+	rec = func(i int, cur [][]*Room, idxs []int) {
 		if i == len(all) {
 			if len(cur) == 0 {
 				return
 			}
-			lengths := make([]int, len(cur))
-			for j, p := range cur {
-				lengths[j] = len(p) - 1
+			t, cost := solutionCost(g, cur, obj)
+			if t == 0 || t > bestTurns {
+				return
 			}
-			t := ComputeTurns(ants, lengths)
-			better := false
-			if t < bestTurns {
-				better = true
-			} else if t == bestTurns {
-				for k := 0; k < len(idxs) && k < len(bestIdx); k++ {
-					if idxs[k] < bestIdx[k] {
-						better = true
-						break
-					} else if idxs[k] > bestIdx[k] {
-						break
-					}
+			for j := range all {
+				if !alone[j] || copies[j] < 2 {
+					continue
 				}
-				if !better && len(idxs) < len(bestIdx) {
-					better = true
+				// Keep the fewest copies of all[j] that do as well. Fewer
+				// copies never take fewer turns nor cost less, except by
+				// number of paths.
+				s := slices.Index(idxs, j)
+				lo, hi := 1, copies[j]
+				for lo < hi {
+					mid := (lo + hi) / 2
+					if tm, cm := solutionCost(g, slices.Concat(cur[:s+mid], cur[s+copies[j]:]), obj); tm == t && cm <= cost {
+						hi = mid
+					} else {
+						lo = mid + 1
+					}
 				}
+				cur = slices.Concat(cur[:s+lo], cur[s+copies[j]:])
+				idxs = slices.Concat(idxs[:s+lo], idxs[s+copies[j]:])
+				_, cost = solutionCost(g, cur, obj)
 			}
-			if better {
-				bestTurns = t
-				best = append([][]*Room{}, cur...)
-				bestIdx = append([]int{}, idxs...)
+			if crossing {
+				// Waiting at crossings only adds turns to the estimate.
+				if deadlockFree(cur) {
+					candidates = append(candidates, candidate{t, cost, slices.Clone(cur), slices.Clone(idxs)})
+				}
+				return
 			}
+			consider(t, cost, cur, idxs)
 			return
 		}
-		rec(i+1, cur, idxs, used)
+		rec(i+1, cur, idxs)
 		p := all[i]
-		valid := true
-		for _, r := range p[1 : len(p)-1] {
-			if used[r] {
-				valid = false
-				break
+		u := used[group(p)]
+		if alone[i] {
+			for copies[i] < maxCopies(g, p) && u.fits(p) {
+				copies[i]++
+				u.add(p, 1)
+				cur, idxs = append(cur, p), append(idxs, i)
 			}
+			if copies[i] > 0 {
+				rec(i+1, cur, idxs)
+				u.add(p, -copies[i])
+				copies[i] = 0
+			}
+			return
+		}
+		if copies[i] < maxCopies(g, p) && u.fits(p) {
+			// A path may be chosen again while its rooms and tunnels
+			// have spare capacity, each copy carrying its own ants.
+			copies[i]++
+			u.add(p, 1)
+			rec(i, append(cur, p), append(idxs, i))
+			u.add(p, -1)
+			copies[i]--
+		}
+	}
+	rec(0, nil, nil)
+	sort.SliceStable(candidates, func(i, j int) bool { return candidates[i].bound < candidates[j].bound })
+	for _, c := range candidates {
+		if c.bound > bestTurns {
+			break
+		}
+		if t := len(simulate(g, c.paths, routeAnts(g, c.paths, obj))); t > 0 && t <= bestTurns {
+			consider(t, c.cost, c.paths, c.idxs)
+		}
+	}
+	return best
+}
+
+// maxCopies is how many times p may be chosen: no more than there are
+// ants, nor than any of its rooms holds or any of its tunnels lets in per
+// turn, checkpoints included.
+func maxCopies(g *Graph, p []*Room) int {
+	n := g.Ants
+	for _, r := range p[1 : len(p)-1] {
+		n = min(n, r.capacity())
+	}
+	for i := 1; i < len(p); i++ {
+		n = min(n, p[i-1].LinkWidth(p[i]))
+	}
+	return n
+}
+
+// orderedRooms reports whether the rooms of paths can be ordered so that
+// every path visits them in that order. Ants then never wait on each
+// other in a circle, whatever paths they share.
+func orderedRooms(paths [][]*Room) bool {
+	next := map[*Room][]*Room{}
+	for _, p := range paths {
+		for i := 2; i < len(p)-1; i++ {
+			next[p[i-1]] = append(next[p[i-1]], p[i])
 		}
-		if valid {
-			for _, r := range p[1 : len(p)-1] {
-				used[r] = true
+	}
+	const (
+		open = 1
+		done = 2
+	)
+	state := map[*Room]int{}
+	var acyclic func(*Room) bool
+	acyclic = func(r *Room) bool {
+		switch state[r] {
+		case open:
+			return false
+		case done:
+			return true
+		}
+		state[r] = open
+		for _, nb := range next[r] {
+			if !acyclic(nb) {
+				return false
 			}
-			rec(i+1, append(cur, p), append(idxs, i), used)
-			for _, r := range p[1 : len(p)-1] {
-				delete(used, r)
+		}
+		state[r] = done
+		return true
+	}
+	for r := range next {
+		if !acyclic(r) {
+			return false
+		}
+	}
+	return true
+}
+
+// opposed reports whether p and q visit rooms they both pass through in
+// opposite orders or cross a tunnel in opposite directions. Ants heading
+// towards each other could otherwise meet inside it, or wait for each
+// other on both sides of it, so the simulator lets them through the
+// stretch they share one way at a time.
+func opposed(p, q []*Room) bool {
+	at := map[*Room]int{}
+	for j, r := range q {
+		at[r] = j
+	}
+	last := -1
+	for i, r := range p {
+		j, ok := at[r]
+		if !ok {
+			continue
+		}
+		if k, ok := at[p[max(i-1, 0)]]; i > 0 && ok && k == j+1 {
+			return true
+		}
+		if i > 0 && i < len(p)-1 && j > 0 && j < len(q)-1 {
+			if j < last {
+				return true
 			}
+			last = j
 		}
 	}
-	rec(0, nil, nil, map[*Room]bool{})
-	return best
+	return false
+}
+
+// deadlockFree reports whether the ants along paths never wait on each
+// other forever: every set of paths of which no two are opposed must
+// order their rooms as orderedRooms requires, opposed paths taking turns
+// through what they share.
+func deadlockFree(paths [][]*Room) bool {
+	var distinct [][]*Room
+	for i, f := range firstCopies(paths) {
+		if f == i {
+			distinct = append(distinct, paths[i])
+		}
+	}
+	paths = distinct
+	n := len(paths)
+	conflict := make([]int, n) // bit j of conflict[i] set when i and j are opposed
+	found := false
+	for i := range paths {
+		for j := i + 1; j < n; j++ {
+			if opposed(paths[i], paths[j]) {
+				conflict[i] |= 1 << j
+				conflict[j] |= 1 << i
+				found = true
+			}
+		}
+	}
+	if !found {
+		return orderedRooms(paths)
+	}
+	if n > 16 {
+		return false
+	}
+	for set := 1; set < 1<<n; set++ {
+		var sub [][]*Room
+		maximal := true
+		for i := range paths {
+			if set&(1<<i) == 0 {
+				maximal = maximal && conflict[i]&set != 0
+			} else if conflict[i]&set != 0 {
+				sub = nil
+				break
+			} else {
+				sub = append(sub, paths[i])
+			}
+		}
+		if sub != nil && maximal && !orderedRooms(sub) {
+			return false
+		}
+	}
+	return true
+}
+
+// firstCopies returns for each path the index of the first one going
+// through the same rooms, copies of a path behaving alike.
+func firstCopies(paths [][]*Room) []int {
+	first := make([]int, len(paths))
+	seen := map[string]int{}
+	for i, p := range paths {
+		names := make([]string, len(p))
+		for k, r := range p {
+			names[k] = r.Name
+		}
+		key := strings.Join(names, " ")
+		if j, ok := seen[key]; ok {
+			first[i] = j
+		} else {
+			seen[key], first[i] = i, i
+		}
+	}
+	return first
+}
+
+// pathUse counts how many chosen paths pass through each room and tunnel.
+// A room admits as many paths as its capacity and a tunnel as many as its
+// width, the number of ants that may enter it per turn. Shared rooms and
+// their tunnels admit any number of paths.
+type pathUse struct {
+	rooms   map[*Room]int
+	tunnels map[[2]*Room]int
+	shared  map[*Room]bool
+}
+
+func newPathUse() *pathUse {
+	return &pathUse{rooms: map[*Room]int{}, tunnels: map[[2]*Room]int{}, shared: map[*Room]bool{}}
+}
+
+func (u *pathUse) fits(p []*Room) bool {
+	for _, r := range p[1 : len(p)-1] {
+		if !u.shared[r] && u.rooms[r] >= r.capacity() {
+			return false
+		}
+	}
+	for i := 1; i < len(p); i++ {
+		if !u.shared[p[i-1]] && !u.shared[p[i]] && u.tunnels[tunnelOf(p[i-1], p[i])] >= p[i-1].LinkWidth(p[i]) {
+			return false
+		}
+	}
+	return true
+}
+
+// competes reports whether p and q hold a room or tunnel in common that
+// admits a limited number of paths.
+func (u *pathUse) competes(p, q []*Room) bool {
+	for _, r := range p[1 : len(p)-1] {
+		if !u.shared[r] && slices.Contains(q[1:len(q)-1], r) {
+			return true
+		}
+	}
+	for i := 1; i < len(p); i++ {
+		if u.shared[p[i-1]] || u.shared[p[i]] {
+			continue
+		}
+		for j := 1; j < len(q); j++ {
+			if tunnelOf(p[i-1], p[i]) == tunnelOf(q[j-1], q[j]) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// add records delta more copies of p as chosen, fewer when negative.
+func (u *pathUse) add(p []*Room, delta int) {
+	for _, r := range p[1 : len(p)-1] {
+		u.rooms[r] += delta
+	}
+	for i := 1; i < len(p); i++ {
+		u.tunnels[tunnelOf(p[i-1], p[i])] += delta
+	}
+}
+
+// tunnelOf names the tunnel between a and b whatever the direction.
+func tunnelOf(a, b *Room) [2]*Room {
+	if b.Name < a.Name {
+		a, b = b, a
+	}
+	return [2]*Room{a, b}
 }
 
 func FindPaths(g *Graph) [][]*Room {
+	return FindPathsWith(g, ObjectiveNone)
+}
+
+// FindPathsWith is FindPaths choosing among the fastest path sets by obj.
+func FindPathsWith(g *Graph, obj Objective) [][]*Room {
+	return bestDisjointPaths(g, sortedPaths(g), obj)
+}
+
+// sortedPaths enumerates up to MaxPaths paths from each start room,
+// shortest first.
+func sortedPaths(g *Graph) [][]*Room {
 	all := allPaths(g, MaxPaths)
 	sort.SliceStable(all, func(i, j int) bool {
-		li, lj := len(all[i]), len(all[j])
+		li, lj := PathLength(all[i]), PathLength(all[j])
 		if li == lj {
 			return i < j
 		}
 		return li < lj
 	})
-	return bestDisjointPaths(all, g.Ants)
+	return all
+}
+
+// PathTurns returns the number of turns needed to move ants along paths,
+// or 0 when there are no paths.
+func PathTurns(ants int, paths [][]*Room) int {
+	if len(paths) == 0 {
+		return 0
+	}
+	return ComputeTurns(ants, pathLengths(paths))
+}
+
+// PathLength is the number of turns one ant needs to walk p, the sum of
+// its tunnel lengths.
+func PathLength(p []*Room) int {
+	l := 0
+	for i := 1; i < len(p); i++ {
+		l += p[i-1].LinkLength(p[i])
+	}
+	return l
+}
+
+func pathLengths(paths [][]*Room) []int {
+	lengths := make([]int, len(paths))
+	for i, p := range paths {
+		lengths[i] = PathLength(p)
+	}
+	return lengths
 }
 
 func ComputeTurns(ants int, lengths []int) int {
 	for t := 1; ; t++ {
-		total := 0
-		for _, l := range lengths {
-			if t-l >= 0 {
-				total += t - l + 1
+		if delivered(t, lengths) >= ants {
+			return t
+		}
+	}
+}
+
+// delivered is the number of ants that can reach the end within t turns
+// when every path sends one ant per turn.
+func delivered(t int, lengths []int) int {
+	total := 0
+	for _, l := range lengths {
+		if t-l >= 0 {
+			total += t - l + 1
+		}
+	}
+	return total
+}
+
+// startGroups splits the indexes of paths by the start room they leave
+// from, in the order of g's start rooms.
+func startGroups(g *Graph, paths [][]*Room) [][]int {
+	starts := g.starts()
+	groups := make([][]int, len(starts))
+	for i, p := range paths {
+		for j, s := range starts {
+			if p[0] == s {
+				groups[j] = append(groups[j], i)
 			}
 		}
-		if total >= ants {
-			return t
+	}
+	return groups
+}
+
+// solutionCost returns the turns needed to move every ant of g along
+// paths, each start room sending its ants down its own paths, and the
+// cost obj gives that solution. The turns are 0 when a start room holding
+// ants has no path. With checkpoints or colonies they are a lower bound,
+// ants possibly having to wait for each other where paths meet.
+func solutionCost(g *Graph, paths [][]*Room, obj Objective) (int, int) {
+	turns, cost := 0, 0
+	ants := g.startAnts()
+	for i, group := range startGroups(g, paths) {
+		if ants[i] == 0 {
+			continue
+		}
+		if len(group) == 0 {
+			return 0, 0
 		}
+		lengths := make([]int, len(group))
+		for j, p := range group {
+			lengths[j] = PathLength(paths[p])
+		}
+		turns = max(turns, ComputeTurns(ants[i], lengths))
+		cost += objectiveCost(obj, lengths, ants[i])
+	}
+	if turns > 0 {
+		turns = max(turns, checkpointTurns(g, paths))
 	}
+	return turns, cost
 }
 
-func assignPaths(paths [][]*Room, ants int) []int {
-	n := len(paths)
-	lengths := make([]int, n)
+// checkpointTurns is a lower bound on the turns needed by the ants of g
+// to squeeze through the checkpoints along paths: no more ants than its
+// capacity can enter a checkpoint per turn, the first of them after the
+// shortest way there and the last followed by the shortest way on.
+func checkpointTurns(g *Graph, paths [][]*Room) int {
+	turns := 0
+	for _, cp := range g.Checkpoints {
+		before, after := -1, -1
+		for _, p := range paths {
+			i := slices.Index(p, cp)
+			if b := PathLength(p[:i+1]); before < 0 || b < before {
+				before = b
+			}
+			if a := PathLength(p[i:]); after < 0 || a < after {
+				after = a
+			}
+		}
+		turns = max(turns, before+(g.Ants+cp.capacity()-1)/cp.capacity()-1+after)
+	}
+	return turns
+}
+
+// routeAnts chooses the path of every ant of g, distributed for obj:
+// route[i] is the path of ant i+1. Ants are numbered start room by start
+// room and only take paths leaving from their own. When rooms or tunnels
+// close for a while, ants are rather routed around the closures, when
+// some ants are slow, according to their speed, and when some have a
+// deadline, the most urgent first.
+func routeAnts(g *Graph, paths [][]*Room, obj Objective) []int {
+	assign := assignPaths
+	if g.hasClosures() {
+		assign = func(paths [][]*Room, ants int, _ Objective) []int { return assignAround(paths, ants) }
+	} else if speeds := g.antSpeeds(); speeds != nil {
+		assign = func(paths [][]*Room, _ int, _ Objective) []int { return assignSpeeds(paths, speeds) }
+	} else if len(g.Deadlines) > 0 {
+		assign = assignUrgent
+	}
+	if len(g.Starts) == 0 && len(g.Colonies) == 0 {
+		return assign(paths, g.Ants, obj)
+	}
+	var route []int
+	ants := g.startAnts()
+	for i, group := range startGroups(g, paths) {
+		if ants[i] == 0 {
+			continue
+		}
+		sub := make([][]*Room, len(group))
+		for j, p := range group {
+			sub[j] = paths[p]
+		}
+		for _, j := range assign(sub, ants[i], obj) {
+			route = append(route, group[j])
+		}
+	}
+	return route
+}
+
+// assignAround gives each ant in turn the path on which it would arrive
+// first, leaving the start once the previous ant on that path has and
+// when every room and tunnel ahead is open as it reaches them.
+func assignAround(paths [][]*Room, ants int) []int {
+	next := make([]int, len(paths))
+	for i := range next {
+		next[i] = 1
+	}
+	route := make([]int, ants)
+	for a := range route {
+		best, bestArrival, bestLeave := -1, 0, 0
+		for i, p := range paths {
+			leave := openWalk(p, next[i])
+			if arrival := leave + PathLength(p) - 1; best < 0 || arrival < bestArrival {
+				best, bestArrival, bestLeave = i, arrival, leave
+			}
+		}
+		route[a] = best
+		next[best] = bestLeave + 1
+	}
+	return route
+}
+
+// openWalk returns the first turn from turn from on at which an ant can
+// leave the start of p and walk it without waiting for a closed room or
+// tunnel. p must not close for good.
+func openWalk(p []*Room, from int) int {
+	for leave := from; ; leave++ {
+		t, open := leave, true
+		for j := 1; j < len(p) && open; j++ {
+			arrive := t + p[j-1].LinkLength(p[j]) - 1
+			open = !p[j-1].linkClosedAt(p[j], t) && !closedAt(p[j].Closed, arrive)
+			t = arrive + 1
+		}
+		if open {
+			return leave
+		}
+	}
+}
+
+// assignSpeeds gives each ant in turn the path on which it would arrive
+// first, speeds[i] being the turns ant i+1 takes per move. An ant follows
+// the previous ant on its path without passing it, so slow ants numbered
+// last never hold up the fast ones.
+func assignSpeeds(paths [][]*Room, speeds []int) []int {
+	steps := make([]int, len(paths))
 	for i, p := range paths {
-		lengths[i] = len(p) - 1
+		steps[i] = len(stepsOf(p))
+	}
+	ahead := make([][]int, len(paths))
+	route := make([]int, len(speeds))
+	for a, n := range speeds {
+		best := -1
+		var bestWalk []int
+		for i := range paths {
+			walk := followWalk(ahead[i], steps[i], n)
+			if best < 0 || walk[steps[i]-1] < bestWalk[len(bestWalk)-1] {
+				best, bestWalk = i, walk
+			}
+		}
+		route[a] = best
+		ahead[best] = bestWalk
+	}
+	return route
+}
+
+// followWalk returns the turns at which an ant moving every n turns
+// enters each of the steps of a path, behind the ant that entered them at
+// the turns in ahead, or alone when ahead is nil. The ant leaves after the
+// one ahead and enters a place once the one ahead has moved on.
+func followWalk(ahead []int, steps, n int) []int {
+	walk := make([]int, steps)
+	for j := 1; j < steps; j++ {
+		walk[j] = walk[j-1] + n
+		if ahead == nil {
+			continue
+		}
+		if j == 1 {
+			walk[j] = max(walk[j], ahead[1]+1)
+		}
+		if j+1 < steps {
+			walk[j] = max(walk[j], ahead[j+1])
+		}
+	}
+	return walk
+}
+
+func assignPaths(paths [][]*Room, ants int, obj Objective) []int {
+	n := len(paths)
+	counts := pathCounts(pathLengths(paths), ants, obj)
+	var order []int
+	active := true
+	for active {
+		active = false
+		for i := 0; i < n; i++ {
+			if counts[i] > 0 {
+				order = append(order, i)
+				counts[i]--
+				active = true
+			}
+		}
+	}
+	return order
+}
+
+// assignUrgent spreads as many ants over each path as assignPaths, so the
+// turns are the same, but gives the ants, in order, the places arriving
+// first, so that antDeadlines can hand the most urgent deadlines to the
+// lowest numbered ants.
+func assignUrgent(paths [][]*Room, ants int, obj Objective) []int {
+	type place struct{ path, arrival int }
+	var places []place
+	lengths := pathLengths(paths)
+	for i, c := range pathCounts(lengths, ants, obj) {
+		for k := range c {
+			places = append(places, place{i, lengths[i] + k})
+		}
+	}
+	sort.SliceStable(places, func(i, j int) bool {
+		a, b := places[i], places[j]
+		if a.arrival != b.arrival {
+			return a.arrival < b.arrival
+		}
+		return lengths[a.path] < lengths[b.path]
+	})
+	route := make([]int, ants)
+	for a := range route {
+		route[a] = places[a].path
+	}
+	return route
+}
+
+// pathCounts decides how many ants take each path so that all of them
+// arrive within ComputeTurns turns.
+func pathCounts(lengths []int, ants int, obj Objective) []int {
+	switch obj {
+	case ObjectiveMoves:
+		return countsByMoves(lengths, ants)
+	case ObjectiveArrival:
+		return countsByArrival(lengths, ants)
 	}
+	n := len(lengths)
 	t := ComputeTurns(ants, lengths)
 	counts := make([]int, n)
 	for i, l := range lengths {
@@ -146,17 +770,5 @@ func assignPaths(paths [][]*Room, ants int) []int {
 			total -= take
 		}
 	}
-	var order []int
-	active := true
-	for active {
-		active = false
-		for i := 0; i < n; i++ {
-			if counts[i] > 0 {
-				order = append(order, i)
-				counts[i]--
-				active = true
-			}
-		}
-	}
-	return order
+	return counts
 }
@@ -0,0 +1,311 @@
+package utils
+
+import (
+	"bufio"
+	"math"
+	"os"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Event changes a tunnel of the colony just before a turn is played.
+type Event struct {
+	Turn int
+	// Open is set when a new tunnel opens and unset when it collapses.
+	Open bool
+	A, B string
+}
+
+// ScenarioResult is the outcome of RunScenario.
+type ScenarioResult struct {
+	// Moves holds the moves of each turn, as SimulateMulti prints them.
+	Moves []string
+	// Turns is the number of turns with the events and BaselineTurns the
+	// number without them.
+	Turns         int
+	BaselineTurns int
+	// Rerouted counts the ants sent along a new path after leaving start.
+	Rerouted int
+}
+
+// ExtraTurns is what the events cost, negative when they helped.
+func (r ScenarioResult) ExtraTurns() int {
+	return r.Turns - r.BaselineTurns
+}
+
+// ParseScenario reads a scenario file holding one event per line, the
+// turn first: "5 collapse a-b" or "8 open c-d". Blank lines and lines
+// starting with # are ignored. The events are returned in turn order.
+func ParseScenario(path string) ([]Event, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	var events []Event
+	scanner := bufio.NewScanner(file)
+	for n := 1; scanner.Scan(); n++ {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		bad := LemError{"ERROR: invalid scenario", "line " + strconv.Itoa(n) + ": '" + line + "'"}
+		f := strings.Fields(line)
+		if len(f) != 3 || f[1] != "collapse" && f[1] != "open" {
+			return nil, bad
+		}
+		turn, err := strconv.Atoi(f[0])
+		a, b, ok := strings.Cut(f[2], "-")
+		if err != nil || turn < 1 || !ok || a == "" || b == "" {
+			return nil, bad
+		}
+		events = append(events, Event{Turn: turn, Open: f[1] == "open", A: a, B: b})
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	sort.SliceStable(events, func(i, j int) bool { return events[i].Turn < events[j].Turn })
+	return events, nil
+}
+
+// RunScenario moves the ants of g as SimulateWith would, applying events
+// as the turns go by. After the events of a turn, the ants still at start
+// are spread over a fresh choice of paths and the ants on their way whose
+// path lost a tunnel take the shortest way left to end. Ants inside a
+// collapsing long tunnel still get out at its far end. g is not modified.
+func RunScenario(g *Graph, events []Event, obj Objective) (ScenarioResult, error) {
+	if len(g.Starts) > 0 || len(g.Colonies) > 0 {
+		return ScenarioResult{}, LemError{"ERROR: invalid scenario", "scenarios need a single start and end room"}
+	}
+	c := g.Clone()
+	paths := FindPathsWith(c, obj)
+	if len(paths) == 0 {
+		return ScenarioResult{}, LemError{"ERROR: invalid data format", "no path from start to end"}
+	}
+	route := routeAnts(c, paths, obj)
+	res := ScenarioResult{BaselineTurns: len(simulate(c, paths, route))}
+
+	sim := newSimulation(c, paths, route)
+	last := lastClosure(c)
+	for !sim.Done() {
+		changed := false
+		for len(events) > 0 && events[0].Turn <= sim.turn+1 {
+			if err := applyEvent(c, events[0]); err != nil {
+				return res, err
+			}
+			events, changed = events[1:], true
+		}
+		if changed {
+			n, err := sim.reroute(obj)
+			if err != nil {
+				return res, err
+			}
+			res.Rerouted += n
+		}
+		before := sim.moves
+		res.Moves = append(res.Moves, formatMoves(c, sim.Step()))
+		if sim.moves == before && len(events) == 0 && sim.turn >= last {
+			return res, LemError{"ERROR: invalid scenario", strconv.Itoa(len(sim.route)-len(sim.finished)) + " ants cannot reach end after turn " + strconv.Itoa(sim.turn)}
+		}
+	}
+	res.Turns = sim.turn
+	return res, nil
+}
+
+// applyEvent opens or collapses the tunnel of e.
+func applyEvent(g *Graph, e Event) error {
+	var err error
+	if e.Open {
+		err = g.AddLink(e.A, e.B)
+	} else {
+		err = g.RemoveLink(e.A, e.B)
+	}
+	if le, ok := err.(LemError); ok {
+		return LemError{"ERROR: invalid scenario", "turn " + strconv.Itoa(e.Turn) + ": " + le.Reason}
+	}
+	return err
+}
+
+// lastClosure is the last turn at which a room or tunnel of g reopens, 0
+// when none ever does.
+func lastClosure(g *Graph) int {
+	last := 0
+	for _, r := range g.Rooms {
+		windows := r.Closed
+		for _, t := range r.Tunnels {
+			windows = append(windows[:len(windows):len(windows)], t.Closed...)
+		}
+		for _, w := range windows {
+			if w.To != math.MaxInt {
+				last = max(last, w.To)
+			}
+		}
+	}
+	return last
+}
+
+// reroute sends the ants still at start along the best paths of the
+// colony as it is now, and the ants on their way whose path is broken
+// along the shortest way left to end through the checkpoints they have
+// yet to pass. Ants at start stay there while no path leads to end. It
+// returns how many ants on their way changed path.
+func (s *Simulation) reroute(obj Objective) (int, error) {
+	var waiting []int
+	for id, started := range s.started {
+		if !started {
+			waiting = append(waiting, id)
+		}
+	}
+	for i := range s.queues {
+		s.queues[i] = nil
+	}
+	if paths, route := s.plan(waiting, obj); len(paths) > 0 {
+		base := len(s.steps)
+		for _, p := range paths {
+			s.addPath(p, stepsOf(p))
+		}
+		for k, ant := range waiting {
+			s.route[ant] = base + route[k]
+			s.queues[base+route[k]] = append(s.queues[base+route[k]], ant)
+		}
+	}
+
+	if s.passed == nil {
+		s.passed = make([]int, len(s.route))
+	}
+	n := 0
+	for id := range s.route {
+		st := s.steps[s.route[id]]
+		if !s.started[id] || s.pos[id] == len(st)-1 {
+			continue
+		}
+		m := s.pos[id]
+		for st[m].room == nil {
+			m++
+		}
+		if intact(st[m:]) {
+			continue
+		}
+		for _, x := range st[:m+1] {
+			if s.passed[id] < len(s.g.Checkpoints) && x.room == s.g.Checkpoints[s.passed[id]] {
+				s.passed[id]++
+			}
+		}
+		rest := shortestPath(s.g, st[m].room, s.passed[id])
+		if rest == nil {
+			return n, LemError{"ERROR: invalid scenario", "ant L" + s.g.AntLabel(id+1) + " cannot reach end from " + st[m].room.Name + " at turn " + strconv.Itoa(s.turn+1)}
+		}
+		s.route[id] = s.addPath(rest, append(append([]step{}, st[s.pos[id]:m]...), stepsOf(rest)...))
+		s.pos[id] = 0
+		n++
+	}
+	return n, nil
+}
+
+// plan chooses paths for the ants still at start in the colony as it is
+// now and spreads them over these paths. It returns no paths for no ants.
+func (s *Simulation) plan(waiting []int, obj Objective) ([][]*Room, []int) {
+	if len(waiting) == 0 {
+		return nil, nil
+	}
+	ants, speeds := s.g.Ants, s.g.Speeds
+	defer func() { s.g.Ants, s.g.Speeds = ants, speeds }()
+	// The waiting ants keep their order, fastest first.
+	if speeds != nil {
+		s.g.Speeds = nil
+		for _, id := range waiting {
+			s.g.Speeds = append(s.g.Speeds, SpeedClass{Every: s.speed[id], Ants: 1})
+		}
+	}
+	s.g.Ants = len(waiting)
+	paths := FindPathsWith(s.g, obj)
+	if len(paths) == 0 {
+		return nil, nil
+	}
+	return paths, routeAnts(s.g, paths, obj)
+}
+
+// addPath adds p, expanded into steps, to the paths of the simulation and
+// returns its index.
+func (s *Simulation) addPath(p []*Room, steps []step) int {
+	s.paths = append(s.paths, p)
+	s.steps = append(s.steps, steps)
+	s.queues = append(s.queues, nil)
+	s.first = append(s.first, len(s.steps)-1)
+	return len(s.steps) - 1
+}
+
+// intact reports whether every room of steps still leads to the next one.
+func intact(steps []step) bool {
+	var prev *Room
+	for _, st := range steps {
+		if st.room == nil {
+			continue
+		}
+		if prev != nil && !hasNeighbor(prev, st.room) {
+			return false
+		}
+		prev = st.room
+	}
+	return true
+}
+
+// shortestPath returns the quickest way from room from to an end room
+// through the checkpoints after the first passed ones, in order, counting
+// tunnel lengths and avoiding start rooms, or nil if there is none.
+func shortestPath(g *Graph, from *Room, passed int) []*Room {
+	p := []*Room{from}
+	for i := passed; i <= len(g.Checkpoints); i++ {
+		goal, avoid := g.isEnd, func(r *Room) bool { return false }
+		if i < len(g.Checkpoints) {
+			goal = func(r *Room) bool { return r == g.Checkpoints[i] }
+			avoid = func(r *Room) bool { return g.isEnd(r) || slices.Contains(g.Checkpoints[i+1:], r) }
+		}
+		leg := shortestLeg(g, p[len(p)-1], goal, avoid)
+		if leg == nil {
+			return nil
+		}
+		p = append(p, leg[1:]...)
+	}
+	return p
+}
+
+// shortestLeg returns the quickest way from room from to a room for which
+// goal holds, never entering start rooms or rooms to avoid, or nil if
+// there is none.
+func shortestLeg(g *Graph, from *Room, goal, avoid func(*Room) bool) []*Room {
+	dist := map[*Room]int{from: 0}
+	prev := map[*Room]*Room{}
+	done := map[*Room]bool{}
+	for {
+		var cur *Room
+		for r, d := range dist {
+			if !done[r] && (cur == nil || d < dist[cur] || d == dist[cur] && r.Name < cur.Name) {
+				cur = r
+			}
+		}
+		if cur == nil {
+			return nil
+		}
+		if goal(cur) {
+			p := []*Room{cur}
+			for p[0] != from {
+				p = append([]*Room{prev[p[0]]}, p...)
+			}
+			return p
+		}
+		done[cur] = true
+		for _, nb := range cur.Links {
+			if g.isStart(nb) || avoid(nb) || forbidden(nb.Closed) || forbidden(cur.Tunnels[nb].Closed) {
+				continue
+			}
+			if d, ok := dist[nb]; !ok || dist[cur]+cur.LinkLength(nb) < d {
+				dist[nb] = dist[cur] + cur.LinkLength(nb)
+				prev[nb] = cur
+			}
+		}
+	}
+}
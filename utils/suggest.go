@@ -0,0 +1,74 @@
+package utils
+
+import "math"
+
+// LinkSuggestion is a tunnel proposed by SuggestLinks and the turn count
+// once it and every earlier suggestion have been added.
+type LinkSuggestion struct {
+	From  string
+	To    string
+	Turns int
+}
+
+// SuggestLinks picks up to k new tunnels that reduce the turn count of
+// FindPaths the most, choosing greedily one tunnel at a time. Only rooms
+// at most maxDist apart are considered when maxDist is positive. The
+// search stops early once no single tunnel improves the result.
+func SuggestLinks(g *Graph, k int, maxDist float64) []LinkSuggestion {
+	work := g.Clone()
+	rooms, _ := roomIndex(work)
+	best := PathTurns(work.Ants, FindPaths(work))
+	var res []LinkSuggestion
+	for len(res) < k {
+		var pick *LinkSuggestion
+		pickDist := 0.0
+		for i, a := range rooms {
+			for _, b := range rooms[i+1:] {
+				if linked(a, b) {
+					continue
+				}
+				d := distance(a, b)
+				if maxDist > 0 && d > maxDist {
+					continue
+				}
+				work.AddLink(a.Name, b.Name)
+				t := PathTurns(work.Ants, FindPaths(work))
+				work.RemoveLink(a.Name, b.Name)
+				if t == 0 || (best != 0 && t >= best) {
+					continue
+				}
+				if pick == nil || t < pick.Turns || (t == pick.Turns && d < pickDist) {
+					pick = &LinkSuggestion{From: a.Name, To: b.Name, Turns: t}
+					pickDist = d
+				}
+			}
+		}
+		if pick == nil {
+			break
+		}
+		work.AddLink(pick.From, pick.To)
+		best = pick.Turns
+		res = append(res, *pick)
+	}
+	return res
+}
+
+// distance is the Euclidean distance between the coordinates of two rooms.
+func distance(a, b *Room) float64 {
+	return math.Hypot(float64(a.X-b.X), float64(a.Y-b.Y))
+}
+
+// turnsApart is the distance between two rooms rounded up to whole turns,
+// computed exactly.
+func turnsApart(a, b *Room) int {
+	dx, dy := a.X-b.X, a.Y-b.Y
+	d2 := dx*dx + dy*dy
+	n := int(math.Sqrt(float64(d2)))
+	for n*n < d2 {
+		n++
+	}
+	for n > 0 && (n-1)*(n-1) >= d2 {
+		n--
+	}
+	return n
+}
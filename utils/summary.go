@@ -0,0 +1,79 @@
+package utils
+
+// PathSummary describes one path of a solution.
+type PathSummary struct {
+	Rooms  []string `json:"rooms"`
+	Length int      `json:"length"`
+	Ants   int      `json:"ants"`
+}
+
+// RoomUsage is how many turns a room held an ant.
+type RoomUsage struct {
+	Room  string `json:"room"`
+	Turns int    `json:"turns"`
+}
+
+// Summary describes a simulated solution.
+type Summary struct {
+	Turns int `json:"turns"`
+	// Moves counts every time an ant enters a room, as Metrics does.
+	Moves      int           `json:"moves"`
+	AvgArrival float64       `json:"average_arrival"`
+	Paths      []PathSummary `json:"paths"`
+	// Arrivals holds the arrival turn of each ant, ant 1 first.
+	Arrivals []int `json:"arrivals"`
+	// Utilization lists the intermediate rooms on the paths in path order.
+	Utilization []RoomUsage `json:"utilization"`
+}
+
+// Summarize simulates the ants along paths, distributed for obj, and
+// gathers the resulting metrics.
+func Summarize(g *Graph, paths [][]*Room, obj Objective) Summary {
+	route := routeAnts(g, paths, obj)
+	s := Summary{Arrivals: make([]int, len(route))}
+	for _, p := range paths {
+		rooms := make([]string, len(p))
+		for j, r := range p {
+			rooms[j] = r.Name
+		}
+		s.Paths = append(s.Paths, PathSummary{Rooms: rooms, Length: PathLength(p)})
+	}
+	for _, p := range route {
+		s.Paths[p].Ants++
+	}
+
+	busy := map[*Room]int{}
+	sim := newSimulation(g, paths, route)
+	for !sim.Done() {
+		for _, m := range sim.Step() {
+			s.Moves++
+			if g.isEnd(m.Room) {
+				s.Arrivals[m.Ant-1] = sim.turn
+			}
+		}
+		for st := range sim.occupancy {
+			if st.room != nil {
+				busy[st.room]++
+			}
+		}
+	}
+	s.Turns = sim.turn
+	total := 0
+	for _, a := range s.Arrivals {
+		total += a
+	}
+	if len(route) > 0 {
+		s.AvgArrival = float64(total) / float64(len(route))
+	}
+
+	seen := map[*Room]bool{}
+	for _, p := range paths {
+		for _, r := range p[1 : len(p)-1] {
+			if !seen[r] {
+				seen[r] = true
+				s.Utilization = append(s.Utilization, RoomUsage{Room: r.Name, Turns: busy[r]})
+			}
+		}
+	}
+	return s
+}
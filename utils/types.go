@@ -1,14 +1,95 @@
 package utils
 
+import "math"
+
 const (
 	MaxPaths = 100
 	MaxAnts  = 100000
 )
 
 type Room struct {
-	Name  string
-	X, Y  int
+	Name string
+	X, Y int
+	// Links holds the rooms an ant can move to. A one-way tunnel is only
+	// listed on the room it leaves from.
 	Links []*Room
+	// Capacity is how many ants the room holds at once; 0 means 1. It is
+	// ignored for start and end, which hold any number.
+	Capacity int
+	// Tunnels holds the properties of the links to neighbours that are not
+	// plain one-turn tunnels.
+	Tunnels map[*Room]Tunnel
+	// Closed lists the turns during which no ant may enter the room.
+	Closed []Window
+}
+
+// Tunnel holds the optional properties of a link. The zero value is a
+// plain tunnel crossed in one turn.
+type Tunnel struct {
+	// Length is the number of turns needed to cross the tunnel; 0 means 1.
+	Length int
+	// Width is how many ants may enter the tunnel per turn; 0 means 1.
+	Width int
+	// Closed lists the turns during which no ant may enter the tunnel.
+	Closed []Window
+}
+
+// Window is the range of turns From to To, both included. A window
+// ending at math.MaxInt closes a room or tunnel for good.
+type Window struct {
+	From, To int
+}
+
+// closedAt reports whether turn falls in one of windows.
+func closedAt(windows []Window, turn int) bool {
+	for _, w := range windows {
+		if w.From <= turn && turn <= w.To {
+			return true
+		}
+	}
+	return false
+}
+
+// forbidden reports whether windows close something for good, from some
+// turn on. Ants could not count on getting through it before.
+func forbidden(windows []Window) bool {
+	for _, w := range windows {
+		if w.To == math.MaxInt {
+			return true
+		}
+	}
+	return false
+}
+
+// capacity returns how many ants an intermediate room holds at once.
+func (r *Room) capacity() int {
+	if r.Capacity > 1 {
+		return r.Capacity
+	}
+	return 1
+}
+
+// LinkWidth returns how many ants may enter the tunnel to the neighbour to
+// in a single turn.
+func (r *Room) LinkWidth(to *Room) int {
+	if w := r.Tunnels[to].Width; w > 1 {
+		return w
+	}
+	return 1
+}
+
+// linkClosedAt reports whether ants may not enter the tunnel to the
+// neighbour to at turn.
+func (r *Room) linkClosedAt(to *Room, turn int) bool {
+	return closedAt(r.Tunnels[to].Closed, turn)
+}
+
+// LinkLength returns the number of turns needed to reach the neighbour to.
+func (r *Room) LinkLength(to *Room) int {
+	if l := r.Tunnels[to].Length; l > 1 {
+		return l
+	}
+	return 1
 }
 
 type Graph struct {
@@ -16,6 +97,49 @@ type Graph struct {
 	Rooms map[string]*Room
 	Start *Room
 	End   *Room
+	// Starts and Ends list every start and end room of a colony that has
+	// several, Start and End being the first of each, and StartAnts the
+	// number of ants in each start room. They are empty otherwise.
+	Starts    []*Room
+	StartAnts []int
+	Ends      []*Room
+	// Colonies lists the groups of ants sharing the rooms, each travelling
+	// from its own start room to its own end room. Start and End are then
+	// those of the first colony and Ants the total of all of them.
+	Colonies []Colony
+	// Checkpoints lists the rooms every ant must pass through, in order.
+	Checkpoints []*Room
+	// Speeds lists the groups of ants that move less often than every
+	// turn; the other ants move every turn.
+	Speeds []SpeedClass
+	// Deadlines lists the groups of ants that must reach end by a given
+	// turn.
+	Deadlines []Deadline
+	// Euclid makes AddLink derive the length of a tunnel from the distance
+	// between its rooms, rounded up to whole turns.
+	Euclid bool
+
+	coords map[[2]int]*Room // rooms by coordinates, kept by AddRoom and RemoveRoom
+}
+
+// SpeedClass is a group of ants that move at most once every Every turns.
+type SpeedClass struct {
+	Every int
+	Ants  int
+}
+
+// Colony is a named group of ants. Its ants are labelled by the colony
+// name followed by their number within it, as in La3.
+type Colony struct {
+	Name       string
+	Start, End *Room
+	Ants       int
+}
+
+// Deadline is a group of ants that must reach end by turn Turn.
+type Deadline struct {
+	Turn int
+	Ants int
 }
 
 type LemError struct {